@@ -0,0 +1,336 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the LGPL license, see LICENSE file for details.
+
+package ofga
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/juju/zaputil/zapctx"
+	"go.uber.org/zap"
+)
+
+// exportedTuple is the JSON representation of a relationship tuple used by
+// ExportTuples.
+type exportedTuple struct {
+	Object    string             `json:"object"`
+	Relation  string             `json:"relation"`
+	Target    string             `json:"target"`
+	Condition *exportedCondition `json:"condition,omitempty"`
+}
+
+// exportedCondition is the JSON representation of the condition attached to
+// a relationship tuple.
+type exportedCondition struct {
+	Name    string         `json:"name"`
+	Context map[string]any `json:"context,omitempty"`
+}
+
+// newExportedTuple returns the JSON representation of the given tuple.
+func newExportedTuple(t Tuple) exportedTuple {
+	et := exportedTuple{
+		Relation: t.Relation.String(),
+	}
+	if t.Object != nil {
+		et.Object = t.Object.String()
+	}
+	if t.Target != nil {
+		et.Target = t.Target.String()
+	}
+	if t.Condition != nil {
+		et.Condition = &exportedCondition{
+			Name:    t.Condition.Name,
+			Context: t.Condition.Context,
+		}
+	}
+	return et
+}
+
+// toTuple converts the JSON representation of a tuple into a Tuple,
+// validating that all of its components are specified and well-formed (see
+// Tuple.Validate).
+func (et exportedTuple) toTuple() (Tuple, error) {
+	t, err := ParseTuple(et.Object, et.Relation, et.Target)
+	if err != nil {
+		return Tuple{}, err
+	}
+	if et.Condition != nil {
+		t.Condition = &Condition{
+			Name:    et.Condition.Name,
+			Context: et.Condition.Context,
+		}
+	}
+	if err := t.Validate(); err != nil {
+		return Tuple{}, err
+	}
+	return t, nil
+}
+
+// ExportTuples writes all relationship tuples stored in the store to w, as
+// newline-delimited JSON objects (NDJSON) of the form:
+//
+//	{"object":"user:123","relation":"editor","target":"document:ABC"}
+//
+// Conditions attached to tuples are included in a "condition" field, holding
+// the condition "name" and its "context". Tuples are fetched one page at a
+// time, and the context is checked for cancellation between pages. The number
+// of tuples written is returned, even if an error occurs.
+func (c *Client) ExportTuples(ctx context.Context, w io.Writer) (int, error) {
+	return c.ExportTuplesWithOptions(ctx, w, ExportTuplesOptions{})
+}
+
+// ExportTuplesOptions holds optional parameters for the
+// ExportTuplesWithOptions method.
+type ExportTuplesOptions struct {
+	// Kinds restricts the export to the tuples whose target is of one of the
+	// given kinds (e.g. "document" and "folder"). All tuples are exported if
+	// no kinds are specified.
+	//
+	// Note that the OpenFGA Read API does not allow reading all the tuples of
+	// a given target kind, so all tuples are still read from the store and
+	// the ones of other kinds are skipped: restricting the export reduces the
+	// size of the output, not the number of requests.
+	Kinds []Kind
+}
+
+// ExportTuplesWithOptions behaves like ExportTuples, accepting additional
+// options.
+func (c *Client) ExportTuplesWithOptions(ctx context.Context, w io.Writer, opts ExportTuplesOptions) (int, error) {
+	enc := json.NewEncoder(w)
+	count := 0
+	continuationToken := ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return count, fmt.Errorf("cannot export tuples: %w", err)
+		}
+		tuples, token, err := c.FindMatchingTuples(ctx, Tuple{}, 0, continuationToken)
+		if err != nil {
+			return count, fmt.Errorf("cannot export tuples: %w", err)
+		}
+		for _, t := range tuples {
+			if len(opts.Kinds) > 0 && (t.Tuple.Target == nil || !slices.Contains(opts.Kinds, t.Tuple.Target.Kind)) {
+				continue
+			}
+			if err := enc.Encode(newExportedTuple(t.Tuple)); err != nil {
+				return count, fmt.Errorf("cannot write tuple: %v", err)
+			}
+			count++
+		}
+		if token == "" || token == continuationToken {
+			break
+		}
+		continuationToken = token
+	}
+	zapctx.Debug(ctx, "exported tuples", zap.Int("count", count))
+	return count, nil
+}
+
+// maxImportLineSize is the maximum size of a single line read by ImportTuples.
+const maxImportLineSize = 1024 * 1024
+
+// ImportTuples reads relationship tuples from r, in the newline-delimited JSON
+// format produced by ExportTuples, and adds them to the store. Empty lines are
+// ignored. Each tuple is validated before being written, and tuples are
+// written in batches of batchSize tuples. Note that OpenFGA limits the number
+// of tuples that can be written in a single request (100 by default).
+//
+// Tuples that already exist in the store are skipped (see AddRelationSafe),
+// so that re-running a partially applied import is safe. When writing a batch
+// fails, the following batches are still written, and the errors of all the
+// failed batches, reporting the lines of their tuples, are returned joined.
+// The number of imported tuples (including any that were already present) is
+// returned, even if an error occurs.
+func (c *Client) ImportTuples(ctx context.Context, r io.Reader, batchSize int) (int, error) {
+	if batchSize < 1 {
+		return 0, errors.New("batchSize must be greater than or equal to 1")
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxImportLineSize)
+
+	count := 0
+	batch := make([]Tuple, 0, batchSize)
+	var errs []error
+	line, batchLine := 0, 0
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := c.AddRelationSafe(ctx, batch...); err != nil {
+			errs = append(errs, fmt.Errorf("cannot import tuples on lines %d-%d: %w", batchLine, line, err))
+		} else {
+			count += len(batch)
+		}
+		batch = batch[:0]
+	}
+
+	for scanner.Scan() {
+		line++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		var et exportedTuple
+		if err := json.Unmarshal(data, &et); err != nil {
+			return count, fmt.Errorf("cannot parse tuple on line %d: %v", line, err)
+		}
+		t, err := et.toTuple()
+		if err != nil {
+			return count, fmt.Errorf("invalid tuple on line %d: %v", line, err)
+		}
+		if len(batch) == 0 {
+			batchLine = line
+		}
+		batch = append(batch, t)
+		if len(batch) == batchSize {
+			flush()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return count, fmt.Errorf("cannot read tuples after line %d: %v", line, err)
+	}
+	flush()
+	if err := errors.Join(errs...); err != nil {
+		return count, err
+	}
+	zapctx.Debug(ctx, "imported tuples", zap.Int("count", count))
+	return count, nil
+}
+
+// maxTuplesPerWrite is the maximum number of tuples added or removed in a
+// single write request by ReconcileTuples and, by default, by
+// RemoveRelationBatched, matching the default limit enforced by OpenFGA.
+const maxTuplesPerWrite = 100
+
+// ReconcileTuples updates the relationship tuples in the store so that they
+// match the desired tuples: stored tuples that are not desired are removed,
+// and desired tuples that are not stored are added. The numbers of added and
+// removed tuples are returned, even if an error occurs.
+//
+// Tuples are compared by their object, relation, target and condition
+// (including its context, where a missing context is the same as an empty
+// one): a stored tuple whose condition differs from the desired one is
+// removed, and the desired tuple is added with its condition, in the same
+// write request so that access is not revoked in between. Tuples are removed
+// first, then updated and finally added, in batches of up to 100 tuples per
+// write request. When a write request fails, the remaining batches are still
+// written, and the errors of all the failed batches are returned joined, so
+// the store may be left partially reconciled. As the changes to apply are
+// computed from the current content of the store, running ReconcileTuples
+// again completes the reconciliation, and running it on an already reconciled
+// store does not write anything.
+func (c *Client) ReconcileTuples(ctx context.Context, desired []Tuple) (added, removed int, err error) {
+	desiredKeys := make(map[string]bool, len(desired))
+	for _, t := range desired {
+		if err := t.Validate(); err != nil {
+			return 0, 0, fmt.Errorf("invalid desired tuple %s: %v", t, err)
+		}
+		desiredKeys[reconcileKey(t)] = true
+	}
+
+	storedKeys := make(map[string]bool)
+	var toRemove []Tuple
+	continuationToken := ""
+	for {
+		tuples, token, err := c.FindMatchingTuples(ctx, Tuple{}, 0, continuationToken)
+		if err != nil {
+			return 0, 0, fmt.Errorf("cannot read stored tuples: %w", err)
+		}
+		for _, t := range tuples {
+			key := reconcileKey(t.Tuple)
+			storedKeys[key] = true
+			if !desiredKeys[key] {
+				toRemove = append(toRemove, t.Tuple)
+			}
+		}
+		if token == "" || token == continuationToken {
+			break
+		}
+		continuationToken = token
+	}
+	// Stored tuples whose condition changed are updated: they are removed
+	// and the desired tuples added in the same write request.
+	removeIndex := make(map[string]int, len(toRemove))
+	for i, t := range toRemove {
+		removeIndex[t.String()] = i
+	}
+	updated := make([]bool, len(toRemove))
+	var toAdd, toUpdateAdd, toUpdateRemove []Tuple
+	for _, t := range desired {
+		key := reconcileKey(t)
+		if storedKeys[key] {
+			continue
+		}
+		// Avoid adding duplicate desired tuples more than once.
+		storedKeys[key] = true
+		if i, ok := removeIndex[t.String()]; ok && !updated[i] {
+			updated[i] = true
+			toUpdateAdd = append(toUpdateAdd, t)
+			toUpdateRemove = append(toUpdateRemove, toRemove[i])
+			continue
+		}
+		toAdd = append(toAdd, t)
+	}
+	var toRemoveOnly []Tuple
+	for i, t := range toRemove {
+		if !updated[i] {
+			toRemoveOnly = append(toRemoveOnly, t)
+		}
+	}
+	toRemove = toRemoveOnly
+
+	var errs []error
+	for start := 0; start < len(toRemove); start += maxTuplesPerWrite {
+		batch := toRemove[start:min(len(toRemove), start+maxTuplesPerWrite)]
+		if err := c.RemoveRelation(ctx, batch...); err != nil {
+			errs = append(errs, fmt.Errorf("cannot remove tuples %d-%d of %d: %w", start+1, start+len(batch), len(toRemove), err))
+			continue
+		}
+		removed += len(batch)
+	}
+	// Each updated tuple is both removed and added.
+	for start := 0; start < len(toUpdateAdd); start += maxTuplesPerWrite / 2 {
+		end := min(len(toUpdateAdd), start+maxTuplesPerWrite/2)
+		if err := c.AddRemoveRelations(ctx, toUpdateAdd[start:end], toUpdateRemove[start:end]); err != nil {
+			errs = append(errs, fmt.Errorf("cannot update tuples %d-%d of %d: %w", start+1, end, len(toUpdateAdd), err))
+			continue
+		}
+		removed += end - start
+		added += end - start
+	}
+	for start := 0; start < len(toAdd); start += maxTuplesPerWrite {
+		batch := toAdd[start:min(len(toAdd), start+maxTuplesPerWrite)]
+		if err := c.AddRelation(ctx, batch...); err != nil {
+			errs = append(errs, fmt.Errorf("cannot add tuples %d-%d of %d: %w", start+1, start+len(batch), len(toAdd), err))
+			continue
+		}
+		added += len(batch)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return added, removed, err
+	}
+	zapctx.Debug(ctx, "reconciled tuples", zap.Int("added", added), zap.Int("removed", removed))
+	return added, removed, nil
+}
+
+// reconcileKey returns the key used by ReconcileTuples to compare tuples,
+// which includes the condition of the tuple, if any. Missing and empty
+// condition contexts result in the same key.
+func reconcileKey(t Tuple) string {
+	if t.Condition == nil {
+		return t.String()
+	}
+	context := []byte("{}")
+	if len(t.Condition.Context) > 0 {
+		// Map keys are sorted when encoding to JSON, so that equal contexts
+		// result in the same key.
+		context, _ = json.Marshal(t.Condition.Context)
+	}
+	return t.String() + " with " + t.Condition.Name + string(context)
+}
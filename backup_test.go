@@ -0,0 +1,485 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the LGPL license, see LICENSE file for details.
+
+package ofga_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/jarcoal/httpmock"
+	openfga "github.com/openfga/go-sdk"
+
+	"github.com/canonical/ofga"
+	"github.com/canonical/ofga/mockhttp"
+)
+
+func TestClientExportTuples(t *testing.T) {
+	c := qt.New(t)
+
+	client := getTestClient(c)
+
+	cancelledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		about          string
+		ctx            context.Context
+		opts           ofga.ExportTuplesOptions
+		mockRoutes     []*mockhttp.RouteResponder
+		expectedOutput string
+		expectedCount  int
+		expectedErr    string
+	}{{
+		about:       "cancelled context is reported",
+		ctx:         cancelledCtx,
+		expectedErr: "cannot export tuples: context canceled",
+	}, {
+		about: "error raised by the underlying client is returned to the caller",
+		ctx:   context.Background(),
+		mockRoutes: []*mockhttp.RouteResponder{{
+			Route:              ReadRoute,
+			MockResponseStatus: http.StatusBadRequest,
+		}},
+		expectedErr: "cannot export tuples: cannot fetch matching tuples.*",
+	}, {
+		about: "tuples are exported successfully",
+		ctx:   context.Background(),
+		mockRoutes: []*mockhttp.RouteResponder{{
+			Route:              ReadRoute,
+			ExpectedPathParams: []string{validFGAParams.StoreID},
+			ExpectedReqBody: openfga.ReadRequest{
+				Consistency: openfga.CONSISTENCYPREFERENCE_UNSPECIFIED.Ptr(),
+			},
+			MockResponse: openfga.ReadResponse{
+				Tuples: []openfga.Tuple{{
+					Key: openfga.TupleKey{User: "user:abc", Relation: "member", Object: "organization:123"},
+				}, {
+					Key: openfga.TupleKey{
+						User:     "team:eng#member",
+						Relation: "viewer",
+						Object:   "document:xyz",
+						Condition: &openfga.RelationshipCondition{
+							Name:    "in_office_hours",
+							Context: &map[string]any{"timezone": "UTC"},
+						},
+					},
+				}},
+			},
+		}},
+		expectedOutput: `{"object":"user:abc","relation":"member","target":"organization:123"}
+{"object":"team:eng#member","relation":"viewer","target":"document:xyz","condition":{"name":"in_office_hours","context":{"timezone":"UTC"}}}
+`,
+		expectedCount: 2,
+	}, {
+		about: "only tuples with a target of the given kinds are exported",
+		ctx:   context.Background(),
+		opts:  ofga.ExportTuplesOptions{Kinds: []ofga.Kind{"document", "folder"}},
+		mockRoutes: []*mockhttp.RouteResponder{{
+			Route: ReadRoute,
+			ExpectedReqBody: openfga.ReadRequest{
+				Consistency: openfga.CONSISTENCYPREFERENCE_UNSPECIFIED.Ptr(),
+			},
+			MockResponse: openfga.ReadResponse{
+				Tuples: []openfga.Tuple{{
+					Key: openfga.TupleKey{User: "user:abc", Relation: "member", Object: "organization:123"},
+				}, {
+					Key: openfga.TupleKey{User: "user:abc", Relation: "viewer", Object: "document:xyz"},
+				}, {
+					Key: openfga.TupleKey{User: "user:abc", Relation: "viewer", Object: "folder:xyz"},
+				}},
+			},
+		}},
+		expectedOutput: `{"object":"user:abc","relation":"viewer","target":"document:xyz"}
+{"object":"user:abc","relation":"viewer","target":"folder:xyz"}
+`,
+		expectedCount: 2,
+	}}
+
+	for _, test := range tests {
+		test := test
+		c.Run(test.about, func(c *qt.C) {
+			// Set up and configure mock http responders.
+			httpmock.Activate()
+			defer httpmock.DeactivateAndReset()
+			for _, mr := range test.mockRoutes {
+				httpmock.RegisterResponder(mr.Route.Method, mr.Route.Endpoint, mr.Generate())
+			}
+
+			// Execute the test.
+			var buf bytes.Buffer
+			count, err := client.ExportTuplesWithOptions(test.ctx, &buf, test.opts)
+
+			if test.expectedErr != "" {
+				c.Assert(err, qt.ErrorMatches, test.expectedErr)
+			} else {
+				c.Assert(err, qt.IsNil)
+			}
+			c.Assert(count, qt.Equals, test.expectedCount)
+			c.Assert(buf.String(), qt.Equals, test.expectedOutput)
+
+			// Validate that the mock routes were called as expected.
+			for _, mr := range test.mockRoutes {
+				mr.Finish(c)
+			}
+		})
+	}
+}
+
+func TestClientImportTuples(t *testing.T) {
+	c := qt.New(t)
+
+	ctx := context.Background()
+	client := getTestClient(c)
+
+	tests := []struct {
+		about              string
+		input              string
+		batchSize          int
+		mockRoutes         []*mockhttp.RouteResponder
+		expectedCount      int
+		expectedWriteCalls int
+		expectedErr        string
+	}{{
+		about:       "invalid batch size is rejected",
+		batchSize:   0,
+		expectedErr: "batchSize must be greater than or equal to 1",
+	}, {
+		about: "malformed line is reported with its line number",
+		input: `{"object":"user:abc","relation":"member","target":"organization:123"}
+
+{"object":"user:xyz",`,
+		batchSize:   10,
+		expectedErr: "cannot parse tuple on line 3: .*",
+	}, {
+		about:       "invalid tuple is reported with its line number",
+		input:       `{"object":"user:abc","target":"organization:123"}`,
+		batchSize:   10,
+		expectedErr: "invalid tuple on line 1: missing relation",
+	}, {
+		about:       "tuple with an entity set target is rejected",
+		input:       `{"object":"user:abc","relation":"member","target":"organization:123#member"}`,
+		batchSize:   10,
+		expectedErr: "invalid tuple on line 1: target must not be an entity set",
+	}, {
+		about: "error writing tuples is returned to the caller",
+		input: `{"object":"user:abc","relation":"member","target":"organization:123"}`,
+		mockRoutes: []*mockhttp.RouteResponder{{
+			Route:              WriteRoute,
+			MockResponseStatus: http.StatusBadRequest,
+		}, {
+			Route:              ReadRoute,
+			MockResponseStatus: http.StatusBadRequest,
+		}},
+		batchSize:          10,
+		expectedWriteCalls: 1,
+		expectedErr:        "cannot import tuples on lines 1-1: cannot fetch matching tuples.*",
+	}, {
+		about: "errors writing multiple batches are all returned",
+		input: `{"object":"user:abc","relation":"member","target":"organization:123"}
+{"object":"user:def","relation":"member","target":"organization:123"}
+
+{"object":"user:xyz","relation":"member","target":"organization:123"}`,
+		mockRoutes: []*mockhttp.RouteResponder{{
+			Route:              WriteRoute,
+			MockResponseStatus: http.StatusBadRequest,
+		}, {
+			Route:              ReadRoute,
+			MockResponseStatus: http.StatusBadRequest,
+		}},
+		batchSize:          2,
+		expectedWriteCalls: 2,
+		expectedErr:        "(?s)cannot import tuples on lines 1-2: cannot fetch matching tuples.*\ncannot import tuples on lines 4-4: cannot fetch matching tuples.*",
+	}, {
+		about: "tuples are imported in batches",
+		input: `{"object":"user:abc","relation":"member","target":"organization:123"}
+{"object":"user:def","relation":"member","target":"organization:123"}
+{"object":"team:eng#member","relation":"viewer","target":"document:xyz","condition":{"name":"in_office_hours","context":{"timezone":"UTC"}}}
+`,
+		batchSize: 2,
+		mockRoutes: []*mockhttp.RouteResponder{{
+			Route:              WriteRoute,
+			ExpectedPathParams: []string{validFGAParams.StoreID},
+			// Only the last request is validated.
+			ExpectedReqBody: openfga.WriteRequest{
+				Writes: openfga.NewWriteRequestWrites([]openfga.TupleKey{{
+					User:     "team:eng#member",
+					Relation: "viewer",
+					Object:   "document:xyz",
+					Condition: &openfga.RelationshipCondition{
+						Name:    "in_office_hours",
+						Context: &map[string]any{"timezone": "UTC"},
+					},
+				}}),
+				AuthorizationModelId: openfga.PtrString(validFGAParams.AuthModelID),
+			},
+		}},
+		expectedCount:      3,
+		expectedWriteCalls: 2,
+	}, {
+		about: "existing tuples are skipped",
+		input: `{"object":"user:abc","relation":"member","target":"organization:123"}`,
+		mockRoutes: []*mockhttp.RouteResponder{{
+			Route:              WriteRoute,
+			MockResponseStatus: http.StatusBadRequest,
+		}, {
+			Route: ReadRoute,
+			ExpectedReqBody: openfga.ReadRequest{
+				TupleKey:    &openfga.ReadRequestTupleKey{User: openfga.PtrString("user:abc"), Relation: openfga.PtrString("member"), Object: openfga.PtrString("organization:123")},
+				Consistency: openfga.CONSISTENCYPREFERENCE_UNSPECIFIED.Ptr(),
+			},
+			MockResponse: openfga.ReadResponse{
+				Tuples: []openfga.Tuple{{
+					Key: openfga.TupleKey{User: "user:abc", Relation: "member", Object: "organization:123"},
+				}},
+			},
+		}},
+		batchSize:          10,
+		expectedCount:      1,
+		expectedWriteCalls: 1,
+	}}
+
+	for _, test := range tests {
+		test := test
+		c.Run(test.about, func(c *qt.C) {
+			// Set up and configure mock http responders.
+			httpmock.Activate()
+			defer httpmock.DeactivateAndReset()
+			for _, mr := range test.mockRoutes {
+				httpmock.RegisterResponder(mr.Route.Method, mr.Route.Endpoint, mr.Generate())
+			}
+
+			// Execute the test.
+			count, err := client.ImportTuples(ctx, strings.NewReader(test.input), test.batchSize)
+
+			if test.expectedErr != "" {
+				c.Assert(err, qt.ErrorMatches, test.expectedErr)
+			} else {
+				c.Assert(err, qt.IsNil)
+			}
+			c.Assert(count, qt.Equals, test.expectedCount)
+			c.Assert(httpmock.GetCallCountInfo()[WriteRoute.Method+" "+WriteRoute.Endpoint], qt.Equals, test.expectedWriteCalls)
+
+			// Validate that the mock routes were called as expected.
+			for _, mr := range test.mockRoutes {
+				mr.Finish(c)
+			}
+		})
+	}
+}
+
+func TestClientReconcileTuples(t *testing.T) {
+	c := qt.New(t)
+
+	ctx := context.Background()
+	client := getTestClient(c)
+
+	tuple := func(object, relation, target string) ofga.Tuple {
+		t, err := ofga.ParseTuple(object, relation, target)
+		c.Assert(err, qt.IsNil)
+		return t
+	}
+	storedTuples := openfga.ReadResponse{
+		Tuples: []openfga.Tuple{{
+			Key: openfga.TupleKey{User: "user:abc", Relation: "member", Object: "organization:123"},
+		}, {
+			Key: openfga.TupleKey{User: "user:def", Relation: "member", Object: "organization:123"},
+		}},
+	}
+	manyTuples := make([]ofga.Tuple, 150)
+	for i := range manyTuples {
+		manyTuples[i] = tuple(fmt.Sprintf("user:%d", i), "member", "organization:123")
+	}
+
+	tests := []struct {
+		about           string
+		desired         []ofga.Tuple
+		readResponse    *openfga.ReadResponse
+		writeStatus     int
+		expectedWrites  []openfga.WriteRequest
+		expectedAdded   int
+		expectedRemoved int
+		expectedErr     string
+	}{{
+		about:       "invalid desired tuple is rejected",
+		desired:     []ofga.Tuple{{Relation: "member", Target: &entityTestContract}},
+		expectedErr: "invalid desired tuple #member@contract:789: missing object",
+	}, {
+		about:       "error reading the stored tuples is returned to the caller",
+		desired:     []ofga.Tuple{tuple("user:abc", "member", "organization:123")},
+		expectedErr: "cannot read stored tuples: cannot fetch matching tuples.*",
+	}, {
+		about: "nothing is written if the store is already reconciled",
+		desired: []ofga.Tuple{
+			tuple("user:def", "member", "organization:123"),
+			tuple("user:abc", "member", "organization:123"),
+		},
+		readResponse: &storedTuples,
+	}, {
+		about: "tuples are removed and added",
+		desired: []ofga.Tuple{
+			tuple("user:abc", "member", "organization:123"),
+			tuple("user:xyz", "member", "organization:123"),
+			tuple("user:xyz", "member", "organization:123"),
+		},
+		readResponse: &storedTuples,
+		expectedWrites: []openfga.WriteRequest{{
+			Deletes: openfga.NewWriteRequestDeletes([]openfga.TupleKeyWithoutCondition{{
+				User: "user:def", Relation: "member", Object: "organization:123",
+			}}),
+			AuthorizationModelId: openfga.PtrString(validFGAParams.AuthModelID),
+		}, {
+			Writes: openfga.NewWriteRequestWrites([]openfga.TupleKey{{
+				User: "user:xyz", Relation: "member", Object: "organization:123",
+			}}),
+			AuthorizationModelId: openfga.PtrString(validFGAParams.AuthModelID),
+		}},
+		expectedAdded:   1,
+		expectedRemoved: 1,
+	}, {
+		about: "tuples whose condition changed are rewritten",
+		desired: []ofga.Tuple{{
+			Object:    &ofga.Entity{Kind: "user", ID: "abc"},
+			Relation:  "member",
+			Target:    &ofga.Entity{Kind: "organization", ID: "123"},
+			Condition: &ofga.Condition{Name: "in_office_hours", Context: map[string]any{"timezone": "UTC", "start": "9"}},
+		}, {
+			Object:    &ofga.Entity{Kind: "user", ID: "def"},
+			Relation:  "member",
+			Target:    &ofga.Entity{Kind: "organization", ID: "123"},
+			Condition: &ofga.Condition{Name: "in_office_hours", Context: map[string]any{"timezone": "UTC"}},
+		}},
+		readResponse: &openfga.ReadResponse{
+			Tuples: []openfga.Tuple{{
+				Key: openfga.TupleKey{
+					User: "user:abc", Relation: "member", Object: "organization:123",
+					Condition: &openfga.RelationshipCondition{Name: "in_office_hours", Context: &map[string]any{"start": "9", "timezone": "UTC"}},
+				},
+			}, {
+				Key: openfga.TupleKey{
+					User: "user:def", Relation: "member", Object: "organization:123",
+					Condition: &openfga.RelationshipCondition{Name: "in_office_hours", Context: &map[string]any{"timezone": "CET"}},
+				},
+			}},
+		},
+		expectedWrites: []openfga.WriteRequest{{
+			Writes: openfga.NewWriteRequestWrites([]openfga.TupleKey{{
+				User: "user:def", Relation: "member", Object: "organization:123",
+				Condition: &openfga.RelationshipCondition{Name: "in_office_hours", Context: &map[string]any{"timezone": "UTC"}},
+			}}),
+			Deletes: openfga.NewWriteRequestDeletes([]openfga.TupleKeyWithoutCondition{{
+				User: "user:def", Relation: "member", Object: "organization:123",
+			}}),
+			AuthorizationModelId: openfga.PtrString(validFGAParams.AuthModelID),
+		}},
+		expectedAdded:   1,
+		expectedRemoved: 1,
+	}, {
+		about: "missing and empty condition contexts are equivalent",
+		desired: []ofga.Tuple{{
+			Object:    &ofga.Entity{Kind: "user", ID: "abc"},
+			Relation:  "member",
+			Target:    &ofga.Entity{Kind: "organization", ID: "123"},
+			Condition: &ofga.Condition{Name: "in_office_hours"},
+		}},
+		readResponse: &openfga.ReadResponse{
+			Tuples: []openfga.Tuple{{
+				Key: openfga.TupleKey{
+					User: "user:abc", Relation: "member", Object: "organization:123",
+					Condition: &openfga.RelationshipCondition{Name: "in_office_hours", Context: &map[string]any{}},
+				},
+			}},
+		},
+	}, {
+		about:        "tuples are added in batches",
+		desired:      manyTuples,
+		readResponse: &openfga.ReadResponse{},
+		expectedWrites: []openfga.WriteRequest{{
+			Writes:               openfga.NewWriteRequestWrites(ofga.TuplesToTupleKeys(manyTuples[:100])),
+			AuthorizationModelId: openfga.PtrString(validFGAParams.AuthModelID),
+		}, {
+			Writes:               openfga.NewWriteRequestWrites(ofga.TuplesToTupleKeys(manyTuples[100:])),
+			AuthorizationModelId: openfga.PtrString(validFGAParams.AuthModelID),
+		}},
+		expectedAdded: 150,
+	}, {
+		about:        "error writing tuples is returned to the caller",
+		desired:      []ofga.Tuple{tuple("user:xyz", "member", "organization:123")},
+		readResponse: &storedTuples,
+		writeStatus:  http.StatusBadRequest,
+		expectedWrites: []openfga.WriteRequest{{
+			Deletes: openfga.NewWriteRequestDeletes([]openfga.TupleKeyWithoutCondition{{
+				User: "user:abc", Relation: "member", Object: "organization:123",
+			}, {
+				User: "user:def", Relation: "member", Object: "organization:123",
+			}}),
+			AuthorizationModelId: openfga.PtrString(validFGAParams.AuthModelID),
+		}, {
+			Writes: openfga.NewWriteRequestWrites([]openfga.TupleKey{{
+				User: "user:xyz", Relation: "member", Object: "organization:123",
+			}}),
+			AuthorizationModelId: openfga.PtrString(validFGAParams.AuthModelID),
+		}},
+		expectedErr: "(?s)cannot remove tuples 1-2 of 2: cannot add or remove relations.*\ncannot add tuples 1-1 of 1: cannot add or remove relations.*",
+	}, {
+		about:        "errors writing multiple batches are all returned",
+		desired:      manyTuples,
+		readResponse: &openfga.ReadResponse{},
+		writeStatus:  http.StatusBadRequest,
+		expectedWrites: []openfga.WriteRequest{{
+			Writes:               openfga.NewWriteRequestWrites(ofga.TuplesToTupleKeys(manyTuples[:100])),
+			AuthorizationModelId: openfga.PtrString(validFGAParams.AuthModelID),
+		}, {
+			Writes:               openfga.NewWriteRequestWrites(ofga.TuplesToTupleKeys(manyTuples[100:])),
+			AuthorizationModelId: openfga.PtrString(validFGAParams.AuthModelID),
+		}},
+		expectedErr: "(?s)cannot add tuples 1-100 of 150: cannot add or remove relations.*\ncannot add tuples 101-150 of 150: cannot add or remove relations.*",
+	}}
+
+	for _, test := range tests {
+		test := test
+		c.Run(test.about, func(c *qt.C) {
+			// Set up and configure mock http responders.
+			httpmock.Activate()
+			defer httpmock.DeactivateAndReset()
+			if test.readResponse != nil {
+				httpmock.RegisterResponder(ReadRoute.Method, ReadRoute.Endpoint, func(req *http.Request) (*http.Response, error) {
+					return httpmock.NewJsonResponse(http.StatusOK, test.readResponse)
+				})
+			} else {
+				httpmock.RegisterResponder(ReadRoute.Method, ReadRoute.Endpoint, httpmock.NewStringResponder(http.StatusInternalServerError, ""))
+			}
+			var writes []openfga.WriteRequest
+			httpmock.RegisterResponder(WriteRoute.Method, WriteRoute.Endpoint, func(req *http.Request) (*http.Response, error) {
+				var body openfga.WriteRequest
+				if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+					return nil, err
+				}
+				writes = append(writes, body)
+				status := http.StatusOK
+				if test.writeStatus != 0 {
+					status = test.writeStatus
+				}
+				return httpmock.NewJsonResponse(status, map[string]any{})
+			})
+
+			// Execute the test.
+			added, removed, err := client.ReconcileTuples(ctx, test.desired)
+
+			if test.expectedErr != "" {
+				c.Assert(err, qt.ErrorMatches, test.expectedErr)
+			} else {
+				c.Assert(err, qt.IsNil)
+			}
+			c.Assert(added, qt.Equals, test.expectedAdded)
+			c.Assert(removed, qt.Equals, test.expectedRemoved)
+			c.Assert(writes, qt.DeepEquals, test.expectedWrites)
+		})
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the LGPL license, see LICENSE file for details.
+
+package ofga
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CircuitBreakerConfig specifies how the circuit breaker enabled by
+// OpenFGAParams.CircuitBreaker behaves.
+type CircuitBreakerConfig struct {
+	// FailureThreshold specifies the number of consecutive failed requests
+	// after which the circuit breaker opens, and must be greater than or
+	// equal to 1. Requests fail if the server cannot be reached or if it
+	// responds with a 5xx status code.
+	FailureThreshold int
+	// Cooldown specifies how long the circuit breaker stays open, failing
+	// requests without sending them, before letting a single probe request
+	// through to find out whether the server has recovered.
+	Cooldown time.Duration
+}
+
+// CircuitBreakerState represents the state of a circuit breaker.
+type CircuitBreakerState int
+
+const (
+	// CircuitBreakerClosed is the state of a circuit breaker letting requests
+	// through, which is also reported when no circuit breaker is configured.
+	CircuitBreakerClosed CircuitBreakerState = iota
+	// CircuitBreakerOpen is the state of a circuit breaker failing requests
+	// without sending them to the server.
+	CircuitBreakerOpen
+	// CircuitBreakerHalfOpen is the state of a circuit breaker letting a
+	// single probe request through after the cooldown: the circuit breaker
+	// closes if the request succeeds, and opens again otherwise.
+	CircuitBreakerHalfOpen
+)
+
+// String implements fmt.Stringer.
+func (s CircuitBreakerState) String() string {
+	switch s {
+	case CircuitBreakerClosed:
+		return "closed"
+	case CircuitBreakerOpen:
+		return "open"
+	case CircuitBreakerHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// ErrCircuitBreakerOpen is returned, wrapped, by the client methods whose
+// requests are failed without being sent by an open circuit breaker (see
+// OpenFGAParams.CircuitBreaker).
+var ErrCircuitBreakerOpen = errors.New("circuit breaker is open")
+
+// circuitBreaker keeps track of the outcome of requests, failing requests
+// without sending them after too many consecutive failures.
+type circuitBreaker struct {
+	config CircuitBreakerConfig
+	now    func() time.Time
+
+	mu       sync.Mutex
+	state    CircuitBreakerState
+	failures int
+	openedAt time.Time
+}
+
+// newCircuitBreaker returns a closed circuit breaker with the given
+// configuration.
+func newCircuitBreaker(config CircuitBreakerConfig) *circuitBreaker {
+	return &circuitBreaker{
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// allow returns an error if a request must not be sent to the server. When
+// the cooldown has elapsed, the circuit breaker becomes half-open and allows
+// a single probe request, whose outcome must be reported by calling done.
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case CircuitBreakerOpen:
+		if b.now().Sub(b.openedAt) < b.config.Cooldown {
+			return ErrCircuitBreakerOpen
+		}
+		b.state = CircuitBreakerHalfOpen
+		return nil
+	case CircuitBreakerHalfOpen:
+		// A probe request is already in flight.
+		return ErrCircuitBreakerOpen
+	}
+	return nil
+}
+
+// done records the outcome of a request allowed by the circuit breaker.
+func (b *circuitBreaker) done(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if success {
+		b.state = CircuitBreakerClosed
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == CircuitBreakerHalfOpen || b.failures >= b.config.FailureThreshold {
+		b.state = CircuitBreakerOpen
+		b.openedAt = b.now()
+	}
+}
+
+// cancel records that a request allowed by the circuit breaker was cancelled
+// by the caller, which says nothing about the availability of the server. If
+// the request was a probe, the circuit breaker opens again, letting the next
+// request through as a new probe.
+func (b *circuitBreaker) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == CircuitBreakerHalfOpen {
+		b.state = CircuitBreakerOpen
+	}
+}
+
+// currentState returns the current state of the circuit breaker. An open
+// circuit breaker whose cooldown has elapsed is reported as half-open, as the
+// next request is let through.
+func (b *circuitBreaker) currentState() CircuitBreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == CircuitBreakerOpen && b.now().Sub(b.openedAt) >= b.config.Cooldown {
+		return CircuitBreakerHalfOpen
+	}
+	return b.state
+}
+
+// circuitBreakerTransport is an http.RoundTripper failing requests without
+// sending them while its circuit breaker is open.
+type circuitBreakerTransport struct {
+	breaker *circuitBreaker
+	// next holds the transport used to execute the requests. If nil,
+	// http.DefaultTransport is used.
+	next http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *circuitBreakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if err := t.breaker.allow(); err != nil {
+		if req.Body != nil {
+			// The request body must be closed, as required by the
+			// RoundTripper contract.
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	resp, err := next.RoundTrip(req)
+	if err != nil && req.Context().Err() != nil {
+		t.breaker.cancel()
+		return nil, err
+	}
+	t.breaker.done(err == nil && resp.StatusCode < http.StatusInternalServerError)
+	return resp, err
+}
+
+// CircuitBreakerState returns the state of the circuit breaker configured
+// with OpenFGAParams.CircuitBreaker. CircuitBreakerClosed is returned if no
+// circuit breaker is configured.
+func (c *Client) CircuitBreakerState() CircuitBreakerState {
+	if c.breaker == nil {
+		return CircuitBreakerClosed
+	}
+	return c.breaker.currentState()
+}
@@ -0,0 +1,141 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the LGPL license, see LICENSE file for details.
+
+package ofga_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/jarcoal/httpmock"
+	openfga "github.com/openfga/go-sdk"
+
+	"github.com/canonical/ofga"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	c := qt.New(t)
+
+	ctx := context.Background()
+	now := time.Now()
+
+	// Set up and configure the http mocks.
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	status := http.StatusOK
+	calls := 0
+	// cancelRequest, if not nil, is called to cancel the context of the
+	// request being served.
+	var cancelRequest func()
+	httpmock.RegisterResponder(ListStoreRoute.Method, ListStoreRoute.Endpoint, func(req *http.Request) (*http.Response, error) {
+		calls++
+		if cancelRequest != nil {
+			cancelRequest()
+			return nil, req.Context().Err()
+		}
+		return httpmock.NewJsonResponse(status, openfga.ListStoresResponse{})
+	})
+
+	params := validFGAParams
+	params.SkipValidation = true
+	params.CircuitBreaker = &ofga.CircuitBreakerConfig{
+		FailureThreshold: 2,
+		Cooldown:         time.Minute,
+	}
+	client, err := ofga.NewClient(ctx, params)
+	c.Assert(err, qt.IsNil)
+	ofga.SetCircuitBreakerNow(client, func() time.Time { return now })
+
+	listStores := func() error {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		if cancelRequest != nil {
+			cancelRequest = cancel
+		}
+		_, err := client.ListStores(ctx, 0, "")
+		return err
+	}
+
+	// Client errors do not open the circuit breaker.
+	status = http.StatusNotFound
+	for i := 0; i < 3; i++ {
+		c.Assert(listStores(), qt.ErrorMatches, "cannot list stores.*")
+	}
+	c.Assert(client.CircuitBreakerState(), qt.Equals, ofga.CircuitBreakerClosed)
+	c.Assert(calls, qt.Equals, 3)
+
+	// The circuit breaker opens after consecutive server errors.
+	status = http.StatusInternalServerError
+	c.Assert(listStores(), qt.ErrorMatches, "cannot list stores.*")
+	c.Assert(client.CircuitBreakerState(), qt.Equals, ofga.CircuitBreakerClosed)
+	c.Assert(listStores(), qt.ErrorMatches, "cannot list stores.*")
+	c.Assert(client.CircuitBreakerState(), qt.Equals, ofga.CircuitBreakerOpen)
+	c.Assert(calls, qt.Equals, 5)
+
+	// Requests fail without being sent while the circuit breaker is open.
+	status = http.StatusOK
+	err = listStores()
+	c.Assert(err, qt.ErrorMatches, "cannot list stores: .*circuit breaker is open")
+	c.Assert(err, qt.ErrorIs, ofga.ErrCircuitBreakerOpen)
+	_, err = client.CheckRelation(ctx, ofga.Tuple{
+		Object:   &entityTestUser,
+		Relation: relationEditor,
+		Target:   &entityTestContract,
+	})
+	c.Assert(err, qt.ErrorIs, ofga.ErrCircuitBreakerOpen)
+	err = client.AddRelation(ctx, ofga.Tuple{
+		Object:   &entityTestUser,
+		Relation: relationEditor,
+		Target:   &entityTestContract,
+	})
+	c.Assert(err, qt.ErrorIs, ofga.ErrCircuitBreakerOpen)
+	_, _, err = client.FindMatchingTuples(ctx, ofga.Tuple{}, 0, "")
+	c.Assert(err, qt.ErrorIs, ofga.ErrCircuitBreakerOpen)
+	c.Assert(calls, qt.Equals, 5)
+
+	// A failed probe opens the circuit breaker again.
+	now = now.Add(time.Minute)
+	c.Assert(client.CircuitBreakerState(), qt.Equals, ofga.CircuitBreakerHalfOpen)
+	status = http.StatusServiceUnavailable
+	c.Assert(listStores(), qt.ErrorMatches, "cannot list stores.*")
+	c.Assert(client.CircuitBreakerState(), qt.Equals, ofga.CircuitBreakerOpen)
+	c.Assert(calls, qt.Equals, 6)
+	c.Assert(listStores(), qt.ErrorMatches, "cannot list stores: .*circuit breaker is open")
+	c.Assert(calls, qt.Equals, 6)
+
+	// A probe cancelled by the caller lets the next request through as a
+	// new probe.
+	now = now.Add(time.Minute)
+	cancelRequest = func() {}
+	c.Assert(listStores(), qt.ErrorMatches, "cannot list stores: .*context canceled")
+	c.Assert(client.CircuitBreakerState(), qt.Equals, ofga.CircuitBreakerHalfOpen)
+	c.Assert(calls, qt.Equals, 7)
+	cancelRequest = nil
+
+	// A successful probe closes the circuit breaker.
+	status = http.StatusOK
+	c.Assert(listStores(), qt.IsNil)
+	c.Assert(client.CircuitBreakerState(), qt.Equals, ofga.CircuitBreakerClosed)
+	c.Assert(calls, qt.Equals, 8)
+	c.Assert(listStores(), qt.IsNil)
+	c.Assert(calls, qt.Equals, 9)
+}
+
+func TestCircuitBreakerStateNotConfigured(t *testing.T) {
+	c := qt.New(t)
+
+	client := getTestClient(c)
+	c.Assert(client.CircuitBreakerState(), qt.Equals, ofga.CircuitBreakerClosed)
+}
+
+func TestCircuitBreakerStateString(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(ofga.CircuitBreakerClosed.String(), qt.Equals, "closed")
+	c.Assert(ofga.CircuitBreakerOpen.String(), qt.Equals, "open")
+	c.Assert(ofga.CircuitBreakerHalfOpen.String(), qt.Equals, "half-open")
+	c.Assert(ofga.CircuitBreakerState(42).String(), qt.Equals, "unknown")
+}
@@ -0,0 +1,263 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the LGPL license, see LICENSE file for details.
+
+package ofga
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CachingClient is a decorator over Client that caches the results of
+// CheckRelation calls in memory for a configurable amount of time.
+//
+// Note that cached results may be stale: changes to relationship tuples (or
+// to the authorization model) that are not made through this CachingClient
+// are not observed until the cached entries expire. Writes made through the
+// CachingClient clear the cache, as the authorization model may cause a
+// single tuple to affect the outcome of any check. The cache is cleared even
+// if a write fails, as the outcome of the operation on the server is unknown,
+// and results of checks that were in flight when the cache was cleared are not
+// cached, as they may have been computed before the write.
+// Callers should choose a ttl that reflects how long they are willing to act
+// upon outdated authorization decisions.
+//
+// Only the methods of Client that can be safely combined with the cache are
+// exposed. Other methods, which do not modify tuples, can be called on the
+// wrapped Client directly.
+type CachingClient struct {
+	client *Client
+
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// generation is incremented every time the cache is cleared, so that
+	// results of checks started before then are not cached.
+	generation uint64
+	// lru holds the cache entries ordered from most recently used (front) to
+	// least recently used (back).
+	lru *list.List
+}
+
+// cacheEntry holds a single cached CheckRelation result.
+type cacheEntry struct {
+	key     string
+	allowed bool
+	expires time.Time
+}
+
+// NewCachingClient returns a CachingClient wrapping the given client. Check
+// results are cached for the duration specified by ttl, and at most
+// maxEntries results are kept, evicting the least recently used ones when the
+// limit is reached. A maxEntries value lower than 1 means that the number of
+// entries is not limited.
+func NewCachingClient(c *Client, ttl time.Duration, maxEntries int) *CachingClient {
+	return &CachingClient{
+		client:     c,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		now:        time.Now,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// CheckRelation checks whether the specified relation exists (either directly
+// or indirectly) between the object and the target specified by the tuple,
+// returning a cached result if one is available and has not expired yet. See
+// Client.CheckRelation for more information.
+func (c *CachingClient) CheckRelation(ctx context.Context, tuple Tuple, contextualTuples ...Tuple) (bool, error) {
+	key := checkCacheKey(c.client.StoreID(), c.client.AuthModelID(), tuple, contextualTuples)
+	allowed, generation, ok := c.get(key)
+	if ok {
+		return allowed, nil
+	}
+	allowed, err := c.client.CheckRelation(ctx, tuple, contextualTuples...)
+	if err != nil {
+		return false, err
+	}
+	c.set(key, allowed, generation)
+	return allowed, nil
+}
+
+// AddRelation calls Client.AddRelation and clears the cache.
+func (c *CachingClient) AddRelation(ctx context.Context, tuples ...Tuple) error {
+	defer c.Purge()
+	return c.client.AddRelation(ctx, tuples...)
+}
+
+// AddRelationSafe calls Client.AddRelationSafe and clears the cache.
+func (c *CachingClient) AddRelationSafe(ctx context.Context, tuples ...Tuple) error {
+	defer c.Purge()
+	return c.client.AddRelationSafe(ctx, tuples...)
+}
+
+// AddRelationIfAbsent calls Client.AddRelationIfAbsent and clears the cache.
+func (c *CachingClient) AddRelationIfAbsent(ctx context.Context, tuple Tuple) (created bool, err error) {
+	defer c.Purge()
+	return c.client.AddRelationIfAbsent(ctx, tuple)
+}
+
+// SetRelation calls Client.SetRelation and clears the cache.
+func (c *CachingClient) SetRelation(ctx context.Context, tuple Tuple, grant bool) error {
+	defer c.Purge()
+	return c.client.SetRelation(ctx, tuple, grant)
+}
+
+// RemoveRelation calls Client.RemoveRelation and clears the cache.
+func (c *CachingClient) RemoveRelation(ctx context.Context, tuples ...Tuple) error {
+	defer c.Purge()
+	return c.client.RemoveRelation(ctx, tuples...)
+}
+
+// RemoveRelationBatched calls Client.RemoveRelationBatched and clears the
+// cache.
+func (c *CachingClient) RemoveRelationBatched(ctx context.Context, tuples []Tuple, batchSize int) (int, error) {
+	defer c.Purge()
+	return c.client.RemoveRelationBatched(ctx, tuples, batchSize)
+}
+
+// AddRemoveRelations calls Client.AddRemoveRelations and clears the cache.
+func (c *CachingClient) AddRemoveRelations(ctx context.Context, addTuples, removeTuples []Tuple) error {
+	defer c.Purge()
+	return c.client.AddRemoveRelations(ctx, addTuples, removeTuples)
+}
+
+// ImportTuples calls Client.ImportTuples and clears the cache.
+func (c *CachingClient) ImportTuples(ctx context.Context, r io.Reader, batchSize int) (int, error) {
+	defer c.Purge()
+	return c.client.ImportTuples(ctx, r, batchSize)
+}
+
+// ReconcileTuples calls Client.ReconcileTuples and clears the cache.
+func (c *CachingClient) ReconcileTuples(ctx context.Context, desired []Tuple) (added, removed int, err error) {
+	defer c.Purge()
+	return c.client.ReconcileTuples(ctx, desired)
+}
+
+// StoreID returns the ID of the store used by the wrapped client.
+func (c *CachingClient) StoreID() string {
+	return c.client.StoreID()
+}
+
+// SetStoreID calls Client.SetStoreID and clears the cache.
+func (c *CachingClient) SetStoreID(storeID string) {
+	defer c.Purge()
+	c.client.SetStoreID(storeID)
+}
+
+// AuthModelID returns the ID of the authorization model used by the wrapped
+// client.
+func (c *CachingClient) AuthModelID() string {
+	return c.client.AuthModelID()
+}
+
+// SetAuthModelID calls Client.SetAuthModelID and clears the cache.
+func (c *CachingClient) SetAuthModelID(authModelID string) {
+	defer c.Purge()
+	c.client.SetAuthModelID(authModelID)
+}
+
+// Purge removes all entries from the cache.
+func (c *CachingClient) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+	c.generation++
+}
+
+// get returns the cached result for the given key, if present and not
+// expired, along with the current cache generation.
+func (c *CachingClient) get(key string) (allowed bool, generation uint64, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return false, c.generation, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if !c.now().Before(entry.expires) {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+		return false, c.generation, false
+	}
+	c.lru.MoveToFront(elem)
+	return entry.allowed, c.generation, true
+}
+
+// set stores the result for the given key, evicting the least recently used
+// entry if the cache is full. The result is discarded if the cache has been
+// cleared since the given generation was returned by get.
+func (c *CachingClient) set(key string, allowed bool, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return
+	}
+	expires := c.now().Add(c.ttl)
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*cacheEntry)
+		entry.allowed = allowed
+		entry.expires = expires
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{
+		key:     key,
+		allowed: allowed,
+		expires: expires,
+	})
+	if c.maxEntries > 0 && c.lru.Len() > c.maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// checkCacheKey returns the key used to cache the result of checking the given
+// tuple along with the given contextual tuples, in the given store and
+// authorization model. The store and model are part of the key so that the
+// results are not shared if the wrapped client is reconfigured.
+func checkCacheKey(storeID, authModelID string, tuple Tuple, contextualTuples []Tuple) string {
+	var b strings.Builder
+	b.WriteString(storeID)
+	b.WriteString("/")
+	b.WriteString(authModelID)
+	b.WriteString("|")
+	writeTupleKey(&b, tuple)
+	for _, ct := range contextualTuples {
+		b.WriteString("|")
+		writeTupleKey(&b, ct)
+	}
+	return b.String()
+}
+
+// writeTupleKey writes a string representation of the tuple to the builder.
+func writeTupleKey(b *strings.Builder, tuple Tuple) {
+	if tuple.Object != nil {
+		b.WriteString(tuple.Object.String())
+	}
+	b.WriteString(" ")
+	b.WriteString(tuple.Relation.String())
+	b.WriteString(" ")
+	if tuple.Target != nil {
+		b.WriteString(tuple.Target.String())
+	}
+	if tuple.Condition != nil {
+		b.WriteString(" ")
+		b.WriteString(tuple.Condition.Name)
+		// Map keys are sorted when marshaling, so the resulting key is
+		// deterministic.
+		data, _ := json.Marshal(tuple.Condition.Context)
+		b.Write(data)
+	}
+}
@@ -0,0 +1,262 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the LGPL license, see LICENSE file for details.
+
+package ofga_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/jarcoal/httpmock"
+	openfga "github.com/openfga/go-sdk"
+
+	"github.com/canonical/ofga"
+	"github.com/canonical/ofga/mockhttp"
+)
+
+func TestCachingClientCheckRelation(t *testing.T) {
+	c := qt.New(t)
+
+	ctx := context.Background()
+	now := time.Now()
+	tuple := ofga.Tuple{
+		Object:   &entityTestUser,
+		Relation: relationEditor,
+		Target:   &entityTestContract,
+	}
+	otherTuple := ofga.Tuple{
+		Object:   &entityTestUser2,
+		Relation: relationEditor,
+		Target:   &entityTestContract,
+	}
+
+	tests := []struct {
+		about string
+		// run performs a sequence of operations on the caching client and
+		// returns the final check result.
+		run                func(c *qt.C, cc *ofga.CachingClient) (bool, error)
+		mockRoutes         []*mockhttp.RouteResponder
+		expectedAllowed    bool
+		expectedCheckCalls int
+		expectedErr        string
+	}{{
+		about: "errors are returned to the caller and not cached",
+		run: func(c *qt.C, cc *ofga.CachingClient) (bool, error) {
+			_, err := cc.CheckRelation(ctx, tuple)
+			c.Assert(err, qt.ErrorMatches, "cannot check relation.*")
+			return cc.CheckRelation(ctx, tuple)
+		},
+		mockRoutes: []*mockhttp.RouteResponder{{
+			Route:              CheckRoute,
+			MockResponseStatus: http.StatusBadRequest,
+		}},
+		expectedCheckCalls: 2,
+		expectedErr:        "cannot check relation.*",
+	}, {
+		about: "repeated checks are served from the cache",
+		run: func(c *qt.C, cc *ofga.CachingClient) (bool, error) {
+			_, err := cc.CheckRelation(ctx, tuple)
+			c.Assert(err, qt.IsNil)
+			return cc.CheckRelation(ctx, tuple)
+		},
+		mockRoutes: []*mockhttp.RouteResponder{{
+			Route:        CheckRoute,
+			MockResponse: openfga.CheckResponse{Allowed: openfga.PtrBool(true)},
+		}},
+		expectedAllowed:    true,
+		expectedCheckCalls: 1,
+	}, {
+		about: "checks with different contextual tuples are cached separately",
+		run: func(c *qt.C, cc *ofga.CachingClient) (bool, error) {
+			_, err := cc.CheckRelation(ctx, tuple)
+			c.Assert(err, qt.IsNil)
+			return cc.CheckRelation(ctx, tuple, otherTuple)
+		},
+		mockRoutes: []*mockhttp.RouteResponder{{
+			Route:        CheckRoute,
+			MockResponse: openfga.CheckResponse{Allowed: openfga.PtrBool(true)},
+		}},
+		expectedAllowed:    true,
+		expectedCheckCalls: 2,
+	}, {
+		about: "expired entries are not used",
+		run: func(c *qt.C, cc *ofga.CachingClient) (bool, error) {
+			_, err := cc.CheckRelation(ctx, tuple)
+			c.Assert(err, qt.IsNil)
+			ofga.SetCachingClientNow(cc, func() time.Time { return now.Add(time.Minute) })
+			return cc.CheckRelation(ctx, tuple)
+		},
+		mockRoutes: []*mockhttp.RouteResponder{{
+			Route:        CheckRoute,
+			MockResponse: openfga.CheckResponse{Allowed: openfga.PtrBool(true)},
+		}},
+		expectedAllowed:    true,
+		expectedCheckCalls: 2,
+	}, {
+		about: "least recently used entries are evicted",
+		run: func(c *qt.C, cc *ofga.CachingClient) (bool, error) {
+			_, err := cc.CheckRelation(ctx, tuple)
+			c.Assert(err, qt.IsNil)
+			_, err = cc.CheckRelation(ctx, otherTuple)
+			c.Assert(err, qt.IsNil)
+			return cc.CheckRelation(ctx, tuple)
+		},
+		mockRoutes: []*mockhttp.RouteResponder{{
+			Route:        CheckRoute,
+			MockResponse: openfga.CheckResponse{Allowed: openfga.PtrBool(true)},
+		}},
+		expectedAllowed:    true,
+		expectedCheckCalls: 3,
+	}, {
+		about: "writes clear the cache",
+		run: func(c *qt.C, cc *ofga.CachingClient) (bool, error) {
+			_, err := cc.CheckRelation(ctx, tuple)
+			c.Assert(err, qt.IsNil)
+			err = cc.AddRelation(ctx, tuple)
+			c.Assert(err, qt.IsNil)
+			return cc.CheckRelation(ctx, tuple)
+		},
+		mockRoutes: []*mockhttp.RouteResponder{{
+			Route:        CheckRoute,
+			MockResponse: openfga.CheckResponse{Allowed: openfga.PtrBool(true)},
+		}, {
+			Route: WriteRoute,
+		}},
+		expectedAllowed:    true,
+		expectedCheckCalls: 2,
+	}, {
+		about: "idempotent writes clear the cache",
+		run: func(c *qt.C, cc *ofga.CachingClient) (bool, error) {
+			_, err := cc.CheckRelation(ctx, tuple)
+			c.Assert(err, qt.IsNil)
+			err = cc.SetRelation(ctx, tuple, true)
+			c.Assert(err, qt.IsNil)
+			return cc.CheckRelation(ctx, tuple)
+		},
+		mockRoutes: []*mockhttp.RouteResponder{{
+			Route:        CheckRoute,
+			MockResponse: openfga.CheckResponse{Allowed: openfga.PtrBool(true)},
+		}, {
+			Route: WriteRoute,
+		}},
+		expectedAllowed:    true,
+		expectedCheckCalls: 2,
+	}, {
+		about: "changing the authorization model clears the cache",
+		run: func(c *qt.C, cc *ofga.CachingClient) (bool, error) {
+			_, err := cc.CheckRelation(ctx, tuple)
+			c.Assert(err, qt.IsNil)
+			cc.SetAuthModelID("OtherAuthModelID")
+			c.Assert(cc.AuthModelID(), qt.Equals, "OtherAuthModelID")
+			return cc.CheckRelation(ctx, tuple)
+		},
+		mockRoutes: []*mockhttp.RouteResponder{{
+			Route:        CheckRoute,
+			MockResponse: openfga.CheckResponse{Allowed: openfga.PtrBool(true)},
+		}},
+		expectedAllowed:    true,
+		expectedCheckCalls: 2,
+	}}
+
+	for _, test := range tests {
+		test := test
+		c.Run(test.about, func(c *qt.C) {
+			cc := ofga.NewCachingClient(getTestClient(c), 30*time.Second, 1)
+			ofga.SetCachingClientNow(cc, func() time.Time { return now })
+
+			// Set up and configure mock http responders.
+			httpmock.Activate()
+			defer httpmock.DeactivateAndReset()
+			for _, mr := range test.mockRoutes {
+				httpmock.RegisterResponder(mr.Route.Method, mr.Route.Endpoint, mr.Generate())
+			}
+
+			// Execute the test.
+			allowed, err := test.run(c, cc)
+
+			if test.expectedErr != "" {
+				c.Assert(err, qt.ErrorMatches, test.expectedErr)
+			} else {
+				c.Assert(err, qt.IsNil)
+				c.Assert(allowed, qt.Equals, test.expectedAllowed)
+			}
+			c.Assert(httpmock.GetCallCountInfo()[CheckRoute.Method+" "+CheckRoute.Endpoint], qt.Equals, test.expectedCheckCalls)
+		})
+	}
+}
+
+func TestCachingClientReconfiguredClient(t *testing.T) {
+	c := qt.New(t)
+
+	ctx := context.Background()
+	tuple := ofga.Tuple{
+		Object:   &entityTestUser,
+		Relation: relationEditor,
+		Target:   &entityTestContract,
+	}
+	client := getTestClient(c)
+	cc := ofga.NewCachingClient(client, time.Minute, 0)
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	var storeIDs []string
+	httpmock.RegisterResponder(CheckRoute.Method, CheckRoute.Endpoint, func(req *http.Request) (*http.Response, error) {
+		storeIDs = append(storeIDs, httpmock.MustGetSubmatch(req, 1))
+		return httpmock.NewJsonResponse(http.StatusOK, openfga.CheckResponse{Allowed: openfga.PtrBool(true)})
+	})
+
+	// Results cached for a store are not used when the wrapped client is
+	// configured to use another store.
+	_, err := cc.CheckRelation(ctx, tuple)
+	c.Assert(err, qt.IsNil)
+	client.SetStoreID("01OTHERSTORE0000000000000")
+	_, err = cc.CheckRelation(ctx, tuple)
+	c.Assert(err, qt.IsNil)
+	_, err = cc.CheckRelation(ctx, tuple)
+	c.Assert(err, qt.IsNil)
+	c.Assert(storeIDs, qt.DeepEquals, []string{validFGAParams.StoreID, "01OTHERSTORE0000000000000"})
+}
+
+func TestCachingClientCheckInterleavedWithWrite(t *testing.T) {
+	c := qt.New(t)
+
+	ctx := context.Background()
+	tuple := ofga.Tuple{
+		Object:   &entityTestUser,
+		Relation: relationEditor,
+		Target:   &entityTestContract,
+	}
+	cc := ofga.NewCachingClient(getTestClient(c), time.Minute, 0)
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder(WriteRoute.Method, WriteRoute.Endpoint, httpmock.NewJsonResponderOrPanic(http.StatusOK, map[string]any{}))
+	checks := 0
+	httpmock.RegisterResponder(CheckRoute.Method, CheckRoute.Endpoint, func(req *http.Request) (*http.Response, error) {
+		checks++
+		if checks == 1 {
+			// The relation is added while the first check is in flight, so
+			// its result is computed before the write.
+			err := cc.AddRelation(ctx, tuple)
+			c.Assert(err, qt.IsNil)
+			return httpmock.NewJsonResponse(http.StatusOK, openfga.CheckResponse{Allowed: openfga.PtrBool(false)})
+		}
+		return httpmock.NewJsonResponse(http.StatusOK, openfga.CheckResponse{Allowed: openfga.PtrBool(true)})
+	})
+
+	allowed, err := cc.CheckRelation(ctx, tuple)
+	c.Assert(err, qt.IsNil)
+	c.Assert(allowed, qt.IsFalse)
+
+	// The stale result of the first check has not been cached.
+	allowed, err = cc.CheckRelation(ctx, tuple)
+	c.Assert(err, qt.IsNil)
+	c.Assert(allowed, qt.IsTrue)
+	allowed, err = cc.CheckRelation(ctx, tuple)
+	c.Assert(err, qt.IsNil)
+	c.Assert(allowed, qt.IsTrue)
+	c.Assert(checks, qt.Equals, 2)
+}
@@ -0,0 +1,183 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the LGPL license, see LICENSE file for details.
+
+package ofga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	openfga "github.com/openfga/go-sdk"
+)
+
+// TokenStore persists the continuation token used by a ChangeReader, allowing
+// consumers of the changes API to resume from where they left off (for
+// instance across restarts).
+//
+// Implementations backed by a file or a database only need to store a single
+// string: Load should return the last saved token, or an empty string if no
+// token has been saved yet, and Save should replace the stored token with the
+// given one. For example, a file based store can write the token to a
+// temporary file and rename it over the previous one, while a database backed
+// store can upsert the token in a single row keyed by consumer name.
+type TokenStore interface {
+	// Load returns the last saved continuation token, or an empty string if
+	// no token has been saved.
+	Load() (string, error)
+	// Save stores the given continuation token.
+	Save(token string) error
+}
+
+// MemoryTokenStore is a TokenStore that keeps the continuation token in
+// memory. It is mostly useful for testing and for short-lived consumers that
+// do not need to resume across restarts. The zero value is ready to use.
+type MemoryTokenStore struct {
+	mu    sync.Mutex
+	token string
+}
+
+// Load implements TokenStore by returning the token held in memory.
+func (s *MemoryTokenStore) Load() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.token, nil
+}
+
+// Save implements TokenStore by keeping the token in memory.
+func (s *MemoryTokenStore) Save(token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = token
+	return nil
+}
+
+// ChangeReader reads tuple changes using the ReadChanges API, keeping track of
+// the continuation token in a TokenStore.
+type ChangeReader struct {
+	client     *Client
+	store      TokenStore
+	entityType string
+	pageSize   int32
+}
+
+// NewChangeReader returns a ChangeReader reading changes using the given
+// client and persisting the continuation token in the given store. The
+// entityType and pageSize parameters have the same meaning as in
+// Client.ReadChanges.
+func NewChangeReader(client *Client, store TokenStore, entityType string, pageSize int32) *ChangeReader {
+	return &ChangeReader{
+		client:     client,
+		store:      store,
+		entityType: entityType,
+		pageSize:   pageSize,
+	}
+}
+
+// Read reads all the changes recorded after the stored continuation token,
+// page by page, calling handle for each page of changes. The continuation
+// token is saved only after handle returns successfully for a page, so that a
+// page whose handling failed (or was interrupted) is read again on the next
+// call. Read returns when no more changes are available or when an error
+// occurs.
+func (r *ChangeReader) Read(ctx context.Context, handle func(changes []openfga.TupleChange) error) error {
+	token, err := r.store.Load()
+	if err != nil {
+		return fmt.Errorf("cannot load continuation token: %v", err)
+	}
+	for {
+		resp, err := r.client.ReadChanges(ctx, r.entityType, r.pageSize, token)
+		if err != nil {
+			return err
+		}
+		if len(resp.Changes) == 0 {
+			return nil
+		}
+		if err := handle(resp.Changes); err != nil {
+			return fmt.Errorf("cannot handle changes: %v", err)
+		}
+		next := resp.GetContinuationToken()
+		if err := r.store.Save(next); err != nil {
+			return fmt.Errorf("cannot save continuation token: %v", err)
+		}
+		if next == "" || next == token {
+			return nil
+		}
+		token = next
+	}
+}
+
+// StreamChanges reads tuple changes using the ReadChanges API, starting from
+// the first recorded change, and sends them in order on the returned changes
+// channel. When no more changes are available, the API is polled again after
+// pollInterval, which must be positive: otherwise, an error is sent on the
+// errors channel and both channels are closed. The entityType parameter has
+// the same meaning as in ReadChanges.
+//
+// The changes channel is unbuffered, so no more changes are read while the
+// consumer is busy handling the previous ones. Errors returned when reading
+// changes are sent on the returned errors channel, and reading is retried
+// from the same point after pollInterval, so that no changes are skipped.
+// Both channels must therefore be consumed until they are closed, which
+// happens when the given context is cancelled: at that point the goroutine
+// reading the changes has returned.
+//
+// The stream always starts from the first recorded change. Use a
+// ChangeReader to resume from a persisted continuation token.
+func (c *Client) StreamChanges(ctx context.Context, entityType string, pollInterval time.Duration) (<-chan openfga.TupleChange, <-chan error) {
+	changes := make(chan openfga.TupleChange)
+	errs := make(chan error)
+	go func() {
+		defer close(changes)
+		defer close(errs)
+		if pollInterval <= 0 {
+			select {
+			case errs <- errors.New("pollInterval must be positive"):
+			case <-ctx.Done():
+			}
+			return
+		}
+		var token string
+		for {
+			resp, err := c.ReadChanges(ctx, entityType, 0, token)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				select {
+				case errs <- err:
+				case <-ctx.Done():
+					return
+				}
+			} else {
+				for _, change := range resp.Changes {
+					select {
+					case changes <- change:
+					case <-ctx.Done():
+						return
+					}
+				}
+				next := resp.GetContinuationToken()
+				// Read the next page straight away if there may be more
+				// changes available.
+				more := len(resp.Changes) > 0 && next != "" && next != token
+				if next != "" {
+					token = next
+				}
+				if more {
+					continue
+				}
+			}
+			timer := time.NewTimer(pollInterval)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+		}
+	}()
+	return changes, errs
+}
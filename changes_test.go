@@ -0,0 +1,256 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the LGPL license, see LICENSE file for details.
+
+package ofga_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/jarcoal/httpmock"
+	openfga "github.com/openfga/go-sdk"
+
+	"github.com/canonical/ofga"
+)
+
+// changePages maps continuation tokens to the ReadChanges responses returned
+// by the changes responder.
+var changePages = map[string]openfga.ReadChangesResponse{
+	"": {
+		Changes: []openfga.TupleChange{{
+			TupleKey:  openfga.TupleKey{User: "user:abc", Relation: "member", Object: "organization:1"},
+			Operation: openfga.TUPLEOPERATION_WRITE,
+		}},
+		ContinuationToken: openfga.PtrString("token1"),
+	},
+	"token1": {
+		Changes: []openfga.TupleChange{{
+			TupleKey:  openfga.TupleKey{User: "user:abc", Relation: "member", Object: "organization:2"},
+			Operation: openfga.TUPLEOPERATION_DELETE,
+		}},
+		ContinuationToken: openfga.PtrString("token2"),
+	},
+	"token2": {
+		ContinuationToken: openfga.PtrString("token2"),
+	},
+}
+
+// changesResponder returns the page of changes matching the continuation token
+// of the incoming request.
+func changesResponder(req *http.Request) (*http.Response, error) {
+	return httpmock.NewJsonResponse(http.StatusOK, changePages[req.URL.Query().Get("continuation_token")])
+}
+
+type errorTokenStore struct {
+	loadErr error
+	saveErr error
+}
+
+func (s errorTokenStore) Load() (string, error) {
+	return "", s.loadErr
+}
+
+func (s errorTokenStore) Save(string) error {
+	return s.saveErr
+}
+
+func TestMemoryTokenStore(t *testing.T) {
+	c := qt.New(t)
+
+	var store ofga.MemoryTokenStore
+	token, err := store.Load()
+	c.Assert(err, qt.IsNil)
+	c.Assert(token, qt.Equals, "")
+
+	err = store.Save("token")
+	c.Assert(err, qt.IsNil)
+	token, err = store.Load()
+	c.Assert(err, qt.IsNil)
+	c.Assert(token, qt.Equals, "token")
+}
+
+func TestChangeReaderRead(t *testing.T) {
+	c := qt.New(t)
+
+	ctx := context.Background()
+	client := getTestClient(c)
+
+	tests := []struct {
+		about           string
+		initialToken    string
+		store           ofga.TokenStore
+		handleErr       error
+		responder       httpmock.Responder
+		expectedObjects []string
+		expectedToken   string
+		expectedErr     string
+	}{{
+		about:           "all changes are read and the token is saved",
+		responder:       changesResponder,
+		expectedObjects: []string{"organization:1", "organization:2"},
+		expectedToken:   "token2",
+	}, {
+		about:           "reading resumes from the stored token",
+		initialToken:    "token1",
+		responder:       changesResponder,
+		expectedObjects: []string{"organization:2"},
+		expectedToken:   "token2",
+	}, {
+		about:         "no changes are handled if none are available",
+		initialToken:  "token2",
+		responder:     changesResponder,
+		expectedToken: "token2",
+	}, {
+		about:       "error reading changes is returned to the caller",
+		responder:   httpmock.NewStringResponder(http.StatusInternalServerError, ""),
+		expectedErr: "cannot read changes.*",
+	}, {
+		about:           "token is not saved if handling the changes fails",
+		handleErr:       errors.New("bad wolf"),
+		responder:       changesResponder,
+		expectedObjects: []string{"organization:1"},
+		expectedErr:     "cannot handle changes: bad wolf",
+	}, {
+		about:       "error loading the token is returned to the caller",
+		store:       errorTokenStore{loadErr: errors.New("bad wolf")},
+		expectedErr: "cannot load continuation token: bad wolf",
+	}, {
+		about:           "error saving the token is returned to the caller",
+		store:           errorTokenStore{saveErr: errors.New("bad wolf")},
+		responder:       changesResponder,
+		expectedObjects: []string{"organization:1"},
+		expectedErr:     "cannot save continuation token: bad wolf",
+	}}
+
+	for _, test := range tests {
+		test := test
+		c.Run(test.about, func(c *qt.C) {
+			// Set up and configure mock http responders.
+			httpmock.Activate()
+			defer httpmock.DeactivateAndReset()
+			if test.responder != nil {
+				httpmock.RegisterResponder(ReadChangesRoute.Method, ReadChangesRoute.Endpoint, test.responder)
+			}
+
+			memoryStore := &ofga.MemoryTokenStore{}
+			err := memoryStore.Save(test.initialToken)
+			c.Assert(err, qt.IsNil)
+			store := test.store
+			if store == nil {
+				store = memoryStore
+			}
+
+			// Execute the test.
+			var objects []string
+			reader := ofga.NewChangeReader(client, store, "", 0)
+			err = reader.Read(ctx, func(changes []openfga.TupleChange) error {
+				for _, change := range changes {
+					objects = append(objects, change.TupleKey.Object)
+				}
+				return test.handleErr
+			})
+
+			if test.expectedErr != "" {
+				c.Assert(err, qt.ErrorMatches, test.expectedErr)
+			} else {
+				c.Assert(err, qt.IsNil)
+			}
+			c.Assert(objects, qt.DeepEquals, test.expectedObjects)
+			token, err := memoryStore.Load()
+			c.Assert(err, qt.IsNil)
+			c.Assert(token, qt.Equals, test.expectedToken)
+		})
+	}
+}
+
+func TestClientStreamChanges(t *testing.T) {
+	c := qt.New(t)
+
+	client := getTestClient(c)
+
+	// Set up and configure the http mocks: the first request fails, then the
+	// pages of changes are returned.
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	var (
+		mu     sync.Mutex
+		tokens []string
+	)
+	httpmock.RegisterResponder(ReadChangesRoute.Method, ReadChangesRoute.Endpoint, func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		tokens = append(tokens, req.URL.Query().Get("continuation_token"))
+		if len(tokens) == 1 {
+			return httpmock.NewStringResponse(http.StatusInternalServerError, ""), nil
+		}
+		return changesResponder(req)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, errs := client.StreamChanges(ctx, "", time.Millisecond)
+
+	// The error is reported, and reading is then retried.
+	c.Assert(<-errs, qt.ErrorMatches, "cannot read changes.*")
+	change := <-changes
+	c.Assert(change.TupleKey.Object, qt.Equals, "organization:1")
+	change = <-changes
+	c.Assert(change.TupleKey.Object, qt.Equals, "organization:2")
+
+	// Both channels are closed once the context is cancelled.
+	cancel()
+	for range changes {
+	}
+	for range errs {
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	c.Assert(tokens[:3], qt.DeepEquals, []string{"", "", "token1"})
+	for _, token := range tokens[3:] {
+		c.Assert(token, qt.Equals, "token2")
+	}
+}
+
+func TestClientStreamChangesCancelWhileBlocked(t *testing.T) {
+	c := qt.New(t)
+
+	client := getTestClient(c)
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	served := make(chan struct{})
+	httpmock.RegisterResponder(ReadChangesRoute.Method, ReadChangesRoute.Endpoint, func(req *http.Request) (*http.Response, error) {
+		defer close(served)
+		return changesResponder(req)
+	})
+
+	// The changes are never consumed, so the stream is blocked sending the
+	// first change when the context is cancelled.
+	ctx, cancel := context.WithCancel(context.Background())
+	changes, errs := client.StreamChanges(ctx, "", time.Minute)
+	<-served
+	cancel()
+	for range errs {
+	}
+	_, ok := <-changes
+	c.Assert(ok, qt.IsFalse)
+	c.Assert(httpmock.GetTotalCallCount(), qt.Equals, 1)
+}
+
+func TestClientStreamChangesInvalidPollInterval(t *testing.T) {
+	c := qt.New(t)
+
+	client := getTestClient(c)
+
+	changes, errs := client.StreamChanges(context.Background(), "", 0)
+	c.Assert(<-errs, qt.ErrorMatches, "pollInterval must be positive")
+	_, ok := <-changes
+	c.Assert(ok, qt.IsFalse)
+	_, ok = <-errs
+	c.Assert(ok, qt.IsFalse)
+}
@@ -5,28 +5,48 @@
 package ofga
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/juju/zaputil/zapctx"
 	openfga "github.com/openfga/go-sdk"
 	"github.com/openfga/go-sdk/credentials"
+	"github.com/openfga/go-sdk/oauth2"
 	"github.com/openfga/go-sdk/telemetry"
 	"go.uber.org/zap"
+	"golang.org/x/net/http2"
 )
 
 // OpenFGAParams holds parameters needed to connect to the OpenFGA server.
 type OpenFGAParams struct {
-	// Scheme must be `http` or `https`.
+	// APIURL optionally specifies the full URL of the OpenFGA API (e.g.
+	// `https://gateway.example/fga`), which is useful when the server is
+	// exposed under a path prefix. When specified, it takes precedence over
+	// Scheme, Host and Port.
+	APIURL string
+	// Scheme must be `http` or `https`. If not specified, it defaults to
+	// `https`.
 	Scheme string
 	// Host is the URL to the OpenFGA server and must be specified without the
 	// scheme (i.e. `api.fga.example` instead of `https://api.fga.example`)
 	Host string
-	// Port specifies the port on which the server is running.
+	// Port specifies the port on which the server is running. If not
+	// specified, the default port for the scheme is used (443 for `https` and
+	// 80 for `http`).
 	Port string
 	// Token specifies the authentication token to use while communicating with
 	// the server.
@@ -35,13 +55,163 @@ type OpenFGAParams struct {
 	// authorization checks.
 	StoreID string
 	// AuthModelID specifies the ID of the OpenFGA Authorization model to be
-	// used for authorization checks.
+	// used for authorization checks. If empty (LatestAuthModel), the latest
+	// authorization model in the store is used.
 	AuthModelID string
 	// Telemetry specifies the OpenTelemetry metrics configuration.
 	Telemetry *telemetry.Configuration
 	// HTTPClient optionally specifies http.Client to allow
 	// for advanced customizations.
 	HTTPClient *http.Client
+	// ProxyURL optionally specifies the URL of an HTTP proxy through which
+	// requests to the server are routed (e.g. `http://proxy.example:3128`).
+	// It cannot be specified along with HTTPClient, in which case the proxy
+	// should be configured on the provided client instead.
+	ProxyURL string
+	// MaxIdleConnsPerHost optionally specifies the maximum number of idle
+	// (keep-alive) connections to keep to the server. If not specified,
+	// http.DefaultMaxIdleConnsPerHost is used. It cannot be specified along
+	// with HTTPClient.
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout optionally specifies the maximum amount of time an idle
+	// (keep-alive) connection remains open before closing itself. If not
+	// specified, the timeout of http.DefaultTransport is used. It cannot be
+	// specified along with HTTPClient.
+	IdleConnTimeout time.Duration
+	// ForceHTTP2 makes the client only use HTTP/2 to communicate with the
+	// server, without falling back to HTTP/1.1. When the server is reached
+	// over plain HTTP (e.g. in a trusted network or through a service mesh),
+	// HTTP/2 is used without TLS (h2c), assuming the server supports it. If
+	// not specified, HTTP/2 is still negotiated with servers supporting it
+	// over TLS. It cannot be specified along with HTTPClient or ProxyURL, and
+	// MaxIdleConnsPerHost has no effect on HTTP/2 connections.
+	ForceHTTP2 bool
+	// OnWrite is an optional callback invoked after relationship tuples are
+	// successfully written through the client, receiving exactly the tuples
+	// that were added and removed. It can be used to invalidate external
+	// caches. The callback is called synchronously, after the write request
+	// has succeeded and before the write method returns, so it should not
+	// block for long. It is not called when the write fails.
+	OnWrite func(added, removed []Tuple)
+	// OnCheck is an optional callback invoked after each successful check
+	// request sent by the client (including the ones sent by CheckRelations
+	// and WaitForRelation), receiving the checked tuple and whether the
+	// relation is allowed. It can be used, for instance, to count allowed and
+	// denied checks in business-level metrics, as denial rates may indicate
+	// misconfigurations or attacks. The callback is called synchronously, so
+	// it should not block for long, and it may be called concurrently, e.g.
+	// by CheckRelations. It is not called when the check fails.
+	OnCheck func(tuple Tuple, allowed bool)
+	// StartupRetry optionally configures NewClient to retry the initial
+	// connectivity check, which is useful when the OpenFGA server may not be
+	// ready yet (e.g. when it is started along with the application). If not
+	// specified, a single attempt is made.
+	StartupRetry *StartupRetryConfig
+	// SkipValidation disables the requests made by NewClient to check that
+	// the server can be reached and that the configured store and
+	// authorization model exist, reducing the latency of creating a client
+	// (e.g. in short-lived serverless functions). When set, any
+	// misconfiguration only surfaces when the client is first used.
+	SkipValidation bool
+	// RequestIDContextKey optionally specifies the key under which a request
+	// ID is stored in the context passed to the client methods. When set, the
+	// request ID (a string or a fmt.Stringer) is sent to the server in the
+	// X-Request-Id header of each request, allowing application logs to be
+	// correlated with the server logs. No header is sent when the context
+	// holds no request ID.
+	RequestIDContextKey any
+	// TokenContextKey optionally specifies the key under which a per-request
+	// authentication token (a string) is stored in the context passed to the
+	// client methods. When set, and the context holds a non-empty token, the
+	// token is sent in the Authorization header of the request in place of
+	// Token, allowing a single client to serve requests authenticated as
+	// different principals (e.g. in multi-tenant gateways).
+	//
+	// Security considerations: the key should be of an unexported type, so
+	// that only the code responsible for authenticating incoming requests can
+	// store tokens in the context. Requests made with a context holding no
+	// token fall back to Token, which may grant broader access than the
+	// principal being served. Finally, the results of a CachingClient are
+	// shared across all the requests, regardless of the token used, so a
+	// CachingClient must not be used with per-request tokens.
+	TokenContextKey any
+	// Debug enables logging of the full requests sent to, and responses
+	// received from, the server (including their bodies) through the logger
+	// in the request context, at debug level. Authorization headers are
+	// redacted. This is useful for diagnosing unexpected results, but it
+	// should not be enabled in production as tuples may contain sensitive
+	// information. When enabled, the contextual tuples sent along with check
+	// requests are also logged.
+	Debug bool
+	// ConfigureFunc is an optional escape hatch allowing to set fields of the
+	// underlying OpenFGA client configuration that are not exposed by
+	// OpenFGAParams. It is called with the configuration built from the other
+	// parameters before the credentials are applied and the HTTP client is
+	// wrapped to implement features like Debug or CircuitBreaker, so the
+	// credentials and HTTP client it sets are used along with those features.
+	// Use with care: the configuration is not validated by this library.
+	ConfigureFunc func(*openfga.Configuration)
+	// RequireAuth makes NewClient fail if the requests sent to the server are
+	// not authenticated, i.e. if no credentials resulting in an Authorization
+	// header or OAuth2 tokens are configured, either with Token or by
+	// ConfigureFunc. Per-request tokens (see TokenContextKey) are not taken
+	// into account, as requests without a token fall back to the configured
+	// credentials. This catches a missing token (e.g. an unset environment
+	// variable) when the client is created, rather than when the first
+	// request is rejected by a server requiring authentication.
+	RequireAuth bool
+	// CircuitBreaker optionally enables a circuit breaker, failing requests
+	// immediately, without sending them, with an error wrapping
+	// ErrCircuitBreakerOpen after a number of consecutive requests have
+	// failed because the server could not be reached or returned a server
+	// error. This avoids paying the full cost of timeouts when the server is
+	// unavailable, allowing services to degrade gracefully. The state of the
+	// circuit breaker can be retrieved with Client.CircuitBreakerState, e.g.
+	// to expose it as a metric.
+	CircuitBreaker *CircuitBreakerConfig
+	// ReadOnly makes the client reject all the operations modifying the
+	// content of the server, such as writing tuples, authorization models or
+	// assertions and creating stores, which then return an error wrapping
+	// ErrReadOnly without sending any request. This prevents accidental
+	// writes in services that are only expected to query authorization data.
+	ReadOnly bool
+	// MaxExpandUsers optionally limits the total number of users and usersets
+	// that FindUsersByRelation discovers while recursively expanding the
+	// userset trees returned by the server. When the limit is exceeded, the
+	// expansion stops and an error wrapping ErrExpandLimitExceeded is
+	// returned, protecting services from the memory and latency cost of
+	// expanding huge trees (e.g. public usersets with many members). Users
+	// and usersets are counted each time they are found, including
+	// duplicates. If not specified, no limit is applied.
+	MaxExpandUsers int
+	// ValidateConditions enables the client-side validation of the conditions
+	// of the tuples added through the client: before writing tuples with
+	// conditions, the authorization model in use is retrieved to check that
+	// each condition is defined, and that its context only holds parameters
+	// of the condition. This catches mistakes such as misspelled parameters,
+	// which are otherwise only detected when the condition is evaluated. As
+	// the model is retrieved from the server for each such write, this adds
+	// the latency of an extra request.
+	ValidateConditions bool
+}
+
+// ErrReadOnly is returned, wrapped, by the operations modifying the content
+// of the server when the client is read-only (see OpenFGAParams.ReadOnly).
+var ErrReadOnly = errors.New("client is read-only")
+
+// ErrExpandLimitExceeded is returned, wrapped, by FindUsersByRelation when
+// more users and usersets than allowed by OpenFGAParams.MaxExpandUsers are
+// found.
+var ErrExpandLimitExceeded = errors.New("expand limit exceeded")
+
+// StartupRetryConfig specifies how the initial connectivity check performed by
+// NewClient is retried.
+type StartupRetryConfig struct {
+	// Attempts specifies the maximum number of attempts, and must be greater
+	// than or equal to 1.
+	Attempts int
+	// Interval specifies the time to wait between attempts.
+	Interval time.Duration
 }
 
 // OpenFgaApi defines the methods of the underlying api client that our Client
@@ -53,11 +223,14 @@ type OpenFgaApi interface {
 	GetStore(ctx context.Context, storeID string) openfga.ApiGetStoreRequest
 	ListObjects(ctx context.Context, storeID string) openfga.ApiListObjectsRequest
 	ListStores(ctx context.Context) openfga.ApiListStoresRequest
+	ListUsers(ctx context.Context, storeID string) openfga.ApiListUsersRequest
 	Read(ctx context.Context, storeID string) openfga.ApiReadRequest
+	ReadAssertions(ctx context.Context, storeID string, authModelID string) openfga.ApiReadAssertionsRequest
 	ReadAuthorizationModel(ctx context.Context, storeID string, id string) openfga.ApiReadAuthorizationModelRequest
 	ReadAuthorizationModels(ctx context.Context, storeID string) openfga.ApiReadAuthorizationModelsRequest
 	ReadChanges(ctx context.Context, storeID string) openfga.ApiReadChangesRequest
 	Write(ctx context.Context, storeID string) openfga.ApiWriteRequest
+	WriteAssertions(ctx context.Context, storeID string, authModelID string) openfga.ApiWriteAssertionsRequest
 	WriteAuthorizationModel(ctx context.Context, storeID string) openfga.ApiWriteAuthorizationModelRequest
 }
 
@@ -67,32 +240,49 @@ type OpenFgaApi interface {
 // connect to the specified OpenFGA instance, and verifies the existence of a
 // Store and AuthorizationModel if such IDs are provided during configuration.
 type Client struct {
-	api         OpenFgaApi
-	authModelID string
-	storeID     string
+	api                OpenFgaApi
+	authModelID        string
+	storeID            string
+	onWrite            func(added, removed []Tuple)
+	onCheck            func(tuple Tuple, allowed bool)
+	debug              bool
+	breaker            *circuitBreaker
+	readOnly           bool
+	maxExpandUsers     int
+	validateConditions bool
+	modelConditions    *modelConditionsCache
 }
 
 // NewClient returns a wrapped OpenFGA API client ensuring all calls are made
 // to the provided authorisation model (id) and returns what is necessary.
 func NewClient(ctx context.Context, p OpenFGAParams) (*Client, error) {
-	if p.Host == "" {
-		return nil, errors.New("invalid OpenFGA configuration: missing host")
-	}
-	if p.Port == "" {
-		return nil, errors.New("invalid OpenFGA configuration: missing port")
+	apiURL, err := buildAPIURL(p)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OpenFGA configuration: %v", err)
 	}
 	if p.StoreID == "" && p.AuthModelID != "" {
 		return nil, errors.New("invalid OpenFGA configuration: AuthModelID specified without a StoreID")
 	}
+	if p.StartupRetry != nil && p.SkipValidation {
+		return nil, errors.New("invalid OpenFGA configuration: StartupRetry cannot be specified along with SkipValidation")
+	}
+	if p.StartupRetry != nil && p.StartupRetry.Attempts < 1 {
+		return nil, errors.New("invalid OpenFGA configuration: StartupRetry.Attempts must be greater than or equal to 1")
+	}
+	if p.MaxExpandUsers < 0 {
+		return nil, errors.New("invalid OpenFGA configuration: MaxExpandUsers must not be negative")
+	}
+	if p.CircuitBreaker != nil && p.CircuitBreaker.FailureThreshold < 1 {
+		return nil, errors.New("invalid OpenFGA configuration: CircuitBreaker.FailureThreshold must be greater than or equal to 1")
+	}
 	zapctx.Info(ctx, "configuring OpenFGA client",
-		zap.String("scheme", p.Scheme),
-		zap.String("host", p.Host),
-		zap.String("port", p.Port),
+		zap.String("url", apiURL),
 		zap.String("store", p.StoreID),
 	)
 
 	config := openfga.Configuration{
-		ApiUrl: fmt.Sprintf("%s://%s:%s", p.Scheme, p.Host, p.Port),
+		ApiUrl:    apiURL,
+		Telemetry: p.Telemetry,
 	}
 	if p.Token != "" {
 		config.Credentials = &credentials.Credentials{
@@ -106,21 +296,80 @@ func NewClient(ctx context.Context, p OpenFGAParams) (*Client, error) {
 			Method: credentials.CredentialsMethodNone,
 		}
 	}
-	if p.HTTPClient != nil {
-		config.HTTPClient = p.HTTPClient
-		// When a custom HTTPClient is provided in OpenFGA configuration,
-		// it does not add authorization headers, so we manually add them here.
-		_, headers := config.Credentials.GetHttpClientAndHeaderOverrides()
-		defaultHeaders := make(map[string]string)
-		if len(headers) != 0 {
-			for idx := range headers {
-				defaultHeaders[headers[idx].Key] = headers[idx].Value
-			}
+	if p.ProxyURL != "" || p.MaxIdleConnsPerHost != 0 || p.IdleConnTimeout != 0 || p.ForceHTTP2 {
+		if p.HTTPClient != nil {
+			return nil, errors.New("invalid OpenFGA configuration: ProxyURL, MaxIdleConnsPerHost, IdleConnTimeout and ForceHTTP2 cannot be specified along with HTTPClient")
+		}
+		httpClient, err := newHTTPClient(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OpenFGA configuration: %v", err)
+		}
+		p.HTTPClient = httpClient
+	}
+	config.HTTPClient = p.HTTPClient
+	if p.ConfigureFunc != nil {
+		p.ConfigureFunc(&config)
+	}
+	var headers []*credentials.HeaderParams
+	var oauth2Tokens bool
+	var wrappers []func(next http.RoundTripper) http.RoundTripper
+	if p.TokenContextKey != nil {
+		// Per-request tokens are set closest to the underlying transport, so
+		// that they take precedence over the configured credentials.
+		wrappers = append(wrappers, func(next http.RoundTripper) http.RoundTripper {
+			return &tokenTransport{key: p.TokenContextKey, next: next}
+		})
+	}
+	if config.Credentials != nil {
+		if err := config.Credentials.ValidateCredentialsConfig(); err != nil {
+			return nil, fmt.Errorf("invalid OpenFGA configuration: %v", err)
+		}
+		var wrap func(next http.RoundTripper) http.RoundTripper
+		headers, wrap = credentialsOverrides(config.HTTPClient, config.Credentials)
+		if wrap != nil {
+			wrappers = append(wrappers, wrap)
+			oauth2Tokens = true
 		}
-		config.DefaultHeaders = defaultHeaders
 	}
-	if p.Telemetry != nil {
-		config.Telemetry = p.Telemetry
+	if p.RequireAuth && !oauth2Tokens && !hasAuthorization(headers, config.DefaultHeaders) {
+		return nil, errors.New("invalid OpenFGA configuration: authentication is required but no credentials are specified")
+	}
+	var breaker *circuitBreaker
+	if p.CircuitBreaker != nil {
+		breaker = newCircuitBreaker(*p.CircuitBreaker)
+		wrappers = append(wrappers, func(next http.RoundTripper) http.RoundTripper {
+			return &circuitBreakerTransport{breaker: breaker, next: next}
+		})
+	}
+	if p.Debug {
+		// The Debug field of the SDK configuration is deliberately left
+		// unset: the SDK dumps requests, including the Authorization header,
+		// through the standard library logger, so tokens would be logged
+		// unredacted and outside of the logger in the request context.
+		wrappers = append(wrappers, func(next http.RoundTripper) http.RoundTripper {
+			return &debugTransport{next: next}
+		})
+	}
+	if p.RequestIDContextKey != nil {
+		wrappers = append(wrappers, func(next http.RoundTripper) http.RoundTripper {
+			return &requestIDTransport{key: p.RequestIDContextKey, next: next}
+		})
+	}
+	if config.HTTPClient != nil || len(wrappers) != 0 {
+		// When a custom HTTPClient is provided in OpenFGA configuration, the
+		// SDK does not apply the credentials, so we manually add the
+		// authorization headers here.
+		for _, h := range headers {
+			if config.DefaultHeaders == nil {
+				config.DefaultHeaders = make(map[string]string)
+			}
+			config.DefaultHeaders[h.Key] = h.Value
+		}
+		httpClient := config.HTTPClient
+		for _, wrap := range wrappers {
+			httpClient = withTransport(httpClient, wrap)
+		}
+		config.HTTPClient = httpClient
 	}
 	configuration, err := openfga.NewConfiguration(config)
 	if err != nil {
@@ -129,10 +378,25 @@ func NewClient(ctx context.Context, p OpenFGAParams) (*Client, error) {
 	client := openfga.NewAPIClient(configuration)
 	api := client.OpenFgaApi
 
-	_, _, err = api.ListStores(ctx).Execute()
-	if err != nil {
+	if p.SkipValidation {
+		return &Client{
+			api:                api,
+			authModelID:        p.AuthModelID,
+			storeID:            p.StoreID,
+			onWrite:            p.OnWrite,
+			onCheck:            p.OnCheck,
+			debug:              p.Debug,
+			breaker:            breaker,
+			readOnly:           p.ReadOnly,
+			maxExpandUsers:     p.MaxExpandUsers,
+			validateConditions: p.ValidateConditions,
+			modelConditions:    newModelConditionsCache(),
+		}, nil
+	}
+
+	if err := waitForServer(ctx, api, p.StartupRetry); err != nil {
 		zapctx.Error(ctx, fmt.Sprintf("cannot list stores: %v", err))
-		return nil, fmt.Errorf("cannot list stores: %v", err)
+		return nil, fmt.Errorf("cannot list stores: %w", err)
 	}
 
 	// If StoreID is present, validate that such a store exists.
@@ -140,7 +404,7 @@ func NewClient(ctx context.Context, p OpenFGAParams) (*Client, error) {
 		storeResp, _, err := api.GetStore(ctx, p.StoreID).Execute()
 		if err != nil {
 			zapctx.Error(ctx, fmt.Sprintf("cannot retrieve store: %v", err))
-			return nil, fmt.Errorf("cannot retrieve store: %v", err)
+			return nil, fmt.Errorf("cannot retrieve store: %w", err)
 		}
 		zapctx.Info(ctx, "store found", zap.String("storeName", storeResp.GetName()))
 	}
@@ -150,23 +414,403 @@ func NewClient(ctx context.Context, p OpenFGAParams) (*Client, error) {
 		authModelResp, _, err := api.ReadAuthorizationModel(ctx, p.StoreID, p.AuthModelID).Execute()
 		if err != nil {
 			zapctx.Error(ctx, fmt.Sprintf("cannot retrieve authModel: %v", err))
-			return nil, fmt.Errorf("cannot retrieve authModel: %v", err)
+			return nil, fmt.Errorf("cannot retrieve authModel: %w", err)
 		}
 		zapctx.Info(ctx, "auth model found", zap.String("authModelID", authModelResp.AuthorizationModel.GetId()))
 	}
 	return &Client{
-		api:         api,
-		authModelID: p.AuthModelID,
-		storeID:     p.StoreID,
+		api:                api,
+		authModelID:        p.AuthModelID,
+		storeID:            p.StoreID,
+		onWrite:            p.OnWrite,
+		onCheck:            p.OnCheck,
+		debug:              p.Debug,
+		breaker:            breaker,
+		readOnly:           p.ReadOnly,
+		maxExpandUsers:     p.MaxExpandUsers,
+		validateConditions: p.ValidateConditions,
+		modelConditions:    newModelConditionsCache(),
 	}, nil
 }
 
-// AuthModelID returns the currently configured authorization model ID.
+// NewClientWithNewStore returns a wrapped OpenFGA API client configured to use
+// a newly created store with the given name, along with the ID of the new
+// store. This is mostly useful in tests and demos, where a fresh store is
+// required. The StoreID and AuthModelID params must not be specified.
+func NewClientWithNewStore(ctx context.Context, p OpenFGAParams, storeName string) (*Client, string, error) {
+	if p.StoreID != "" || p.AuthModelID != "" {
+		return nil, "", errors.New("invalid OpenFGA configuration: StoreID and AuthModelID cannot be specified when creating a new store")
+	}
+	client, err := NewClient(ctx, p)
+	if err != nil {
+		return nil, "", err
+	}
+	storeID, err := client.CreateStore(ctx, storeName)
+	if err != nil {
+		return nil, "", err
+	}
+	client.SetStoreID(storeID)
+	return client, storeID, nil
+}
+
+// buildAPIURL returns the URL of the OpenFGA API, either as specified by
+// p.APIURL or built from the scheme, host and port params.
+func buildAPIURL(p OpenFGAParams) (string, error) {
+	if p.APIURL != "" {
+		u, err := url.Parse(p.APIURL)
+		if err != nil {
+			return "", fmt.Errorf("invalid API URL: %v", err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return "", fmt.Errorf("invalid API URL %q: scheme must be http or https", p.APIURL)
+		}
+		if u.Host == "" {
+			return "", fmt.Errorf("invalid API URL %q: missing host", p.APIURL)
+		}
+		if u.RawQuery != "" || u.Fragment != "" {
+			return "", fmt.Errorf("invalid API URL %q: query and fragment are not allowed", p.APIURL)
+		}
+		// API paths are appended to the URL, so a trailing slash would
+		// result in an empty path segment.
+		return strings.TrimSuffix(p.APIURL, "/"), nil
+	}
+	scheme := p.Scheme
+	switch scheme {
+	case "":
+		scheme = "https"
+	case "http", "https":
+	default:
+		return "", errors.New("scheme must be http or https")
+	}
+	if p.Host == "" {
+		return "", errors.New("missing host")
+	}
+	return scheme + "://" + apiHost(scheme, p.Host, p.Port), nil
+}
+
+// apiHost returns the host and port used to reach the OpenFGA server, using
+// the default port for the scheme if no port is specified. IPv6 hosts are
+// enclosed in square brackets.
+func apiHost(scheme, host, port string) string {
+	if port == "" {
+		port = "443"
+		if scheme == "http" {
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(strings.Trim(host, "[]"), port)
+}
+
+// newHTTPClient returns an http.Client whose transport is configured as per
+// the proxy, connection pool and protocol settings specified in the given
+// params.
+func newHTTPClient(p OpenFGAParams) (*http.Client, error) {
+	transport := &http.Transport{}
+	// Start from the default transport settings, unless the default transport
+	// has been replaced with a different implementation.
+	if t, ok := http.DefaultTransport.(*http.Transport); ok {
+		transport = t.Clone()
+	}
+	// Negotiate HTTP/2 with servers supporting it over TLS, allowing
+	// concurrent requests to be multiplexed over a single connection. This is
+	// also the default for http.DefaultTransport, but it must be explicitly
+	// enabled when the default transport has been replaced.
+	transport.ForceAttemptHTTP2 = true
+	if p.ProxyURL != "" {
+		u, err := url.Parse(p.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %v", err)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("invalid proxy URL %q: unsupported scheme %q", p.ProxyURL, u.Scheme)
+		}
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q: missing host", p.ProxyURL)
+		}
+		transport.Proxy = http.ProxyURL(u)
+	}
+	if p.MaxIdleConnsPerHost < 0 {
+		return nil, errors.New("MaxIdleConnsPerHost must not be negative")
+	}
+	if p.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = p.MaxIdleConnsPerHost
+		// Ensure that the overall limit does not prevent the per-host limit
+		// from being reached.
+		if transport.MaxIdleConns != 0 && transport.MaxIdleConns < p.MaxIdleConnsPerHost {
+			transport.MaxIdleConns = p.MaxIdleConnsPerHost
+		}
+	}
+	if p.IdleConnTimeout < 0 {
+		return nil, errors.New("IdleConnTimeout must not be negative")
+	}
+	if p.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = p.IdleConnTimeout
+	}
+	if p.ForceHTTP2 {
+		if p.ProxyURL != "" {
+			return nil, errors.New("ProxyURL cannot be specified along with ForceHTTP2")
+		}
+		return &http.Client{Transport: newHTTP2Transport(transport)}, nil
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+// http2Transport is an http.RoundTripper only using HTTP/2: over TLS for
+// https requests and, for plain http requests, with prior knowledge (h2c).
+type http2Transport struct {
+	tls *http2.Transport
+	h2c *http2.Transport
+}
+
+// newHTTP2Transport returns an http2Transport using the TLS and idle
+// connection settings of the given transport.
+func newHTTP2Transport(t *http.Transport) *http2Transport {
+	return &http2Transport{
+		tls: &http2.Transport{
+			TLSClientConfig: t.TLSClientConfig,
+			IdleConnTimeout: t.IdleConnTimeout,
+		},
+		h2c: &http2.Transport{
+			AllowHTTP: true,
+			// Dial plain TCP connections: the TLS configuration is ignored.
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, addr)
+			},
+			IdleConnTimeout: t.IdleConnTimeout,
+		},
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *http2Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme == "http" {
+		return t.h2c.RoundTrip(req)
+	}
+	return t.tls.RoundTrip(req)
+}
+
+// hasAuthorization reports whether a non-empty Authorization header is
+// included in the given credentials headers or default headers.
+func hasAuthorization(headers []*credentials.HeaderParams, defaultHeaders map[string]string) bool {
+	for _, h := range headers {
+		if http.CanonicalHeaderKey(h.Key) == "Authorization" && h.Value != "" {
+			return true
+		}
+	}
+	for k, v := range defaultHeaders {
+		if http.CanonicalHeaderKey(k) == "Authorization" && v != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// credentialsOverrides returns the headers to be added to each request to
+// authenticate with the given credentials, and, when client credentials are
+// used, a function wrapping transports so that requests are authenticated
+// with OAuth2 tokens. Tokens are retrieved using the given http.Client (or
+// http.DefaultClient if nil).
+func credentialsOverrides(c *http.Client, creds *credentials.Credentials) ([]*credentials.HeaderParams, func(next http.RoundTripper) http.RoundTripper) {
+	if c != nil && creds.Method == credentials.CredentialsMethodClientCredentials && creds.Context == nil {
+		creds.Context = context.WithValue(context.Background(), oauth2.HTTPClient, c)
+	}
+	credsClient, headers := creds.GetHttpClientAndHeaderOverrides()
+	t, ok := credsClient.Transport.(*oauth2.Transport)
+	if !ok {
+		return headers, nil
+	}
+	return headers, func(next http.RoundTripper) http.RoundTripper {
+		return &oauth2.Transport{Source: t.Source, Base: next}
+	}
+}
+
+// withTransport returns a copy of the given http.Client (or of
+// http.DefaultClient if nil) whose transport is replaced by the result of
+// calling wrap with the original transport.
+func withTransport(c *http.Client, wrap func(next http.RoundTripper) http.RoundTripper) *http.Client {
+	if c == nil {
+		c = http.DefaultClient
+	}
+	wrapped := *c
+	wrapped.Transport = wrap(c.Transport)
+	return &wrapped
+}
+
+// requestIDHeader is the header used to send request IDs to the server.
+const requestIDHeader = "X-Request-Id"
+
+// requestIDTransport is an http.RoundTripper adding a request ID header to
+// requests, taking the ID from the request context.
+type requestIDTransport struct {
+	// key holds the key of the request ID in the request context.
+	key any
+	// next holds the transport used to execute the requests. If nil,
+	// http.DefaultTransport is used.
+	next http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *requestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	var id string
+	switch v := req.Context().Value(t.key).(type) {
+	case string:
+		id = v
+	case fmt.Stringer:
+		id = v.String()
+	}
+	if id != "" {
+		// Avoid modifying the original request, as required by the
+		// RoundTripper contract.
+		req = req.Clone(req.Context())
+		req.Header.Set(requestIDHeader, id)
+	}
+	return next.RoundTrip(req)
+}
+
+// tokenTransport is an http.RoundTripper replacing the authentication token
+// sent to the server with the one stored in the request context, if any.
+type tokenTransport struct {
+	// key holds the key of the token in the request context.
+	key any
+	// next holds the transport used to execute the requests. If nil,
+	// http.DefaultTransport is used.
+	next http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if token, _ := req.Context().Value(t.key).(string); token != "" {
+		// Avoid modifying the original request, as required by the
+		// RoundTripper contract.
+		req = req.Clone(req.Context())
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return next.RoundTrip(req)
+}
+
+// debugTransport is an http.RoundTripper logging requests and responses,
+// including their bodies, at debug level.
+type debugTransport struct {
+	// next holds the transport used to execute the requests. If nil,
+	// http.DefaultTransport is used.
+	next http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("cannot read request body: %v", err)
+		}
+		// Avoid modifying the original request, as required by the
+		// RoundTripper contract.
+		req = req.Clone(ctx)
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	zapctx.Debug(ctx, "OpenFGA request",
+		zap.String("method", req.Method),
+		zap.String("url", req.URL.String()),
+		zap.Any("headers", redactHeaders(req.Header)),
+		zap.ByteString("body", body),
+	)
+
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		zapctx.Debug(ctx, "OpenFGA request failed", zap.Error(err))
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read response body: %v", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	zapctx.Debug(ctx, "OpenFGA response",
+		zap.Int("status", resp.StatusCode),
+		zap.Any("headers", redactHeaders(resp.Header)),
+		zap.ByteString("body", respBody),
+	)
+	return resp, nil
+}
+
+// redactHeaders returns a copy of the given headers in which the values of
+// headers containing credentials are redacted.
+func redactHeaders(h http.Header) http.Header {
+	redacted := h.Clone()
+	for _, key := range []string{"Authorization", "Proxy-Authorization"} {
+		if _, ok := redacted[key]; ok {
+			redacted[key] = []string{"REDACTED"}
+		}
+	}
+	return redacted
+}
+
+// waitForServer checks that the OpenFGA server can be reached by listing
+// stores, retrying as specified by the given retry configuration. The error
+// from the last attempt is returned if all attempts fail.
+func waitForServer(ctx context.Context, api OpenFgaApi, retry *StartupRetryConfig) error {
+	attempts := 1
+	var interval time.Duration
+	if retry != nil {
+		attempts = retry.Attempts
+		interval = retry.Interval
+	}
+	for attempt := 1; ; attempt++ {
+		_, _, err := api.ListStores(ctx).Execute()
+		if err == nil || attempt >= attempts {
+			return err
+		}
+		zapctx.Warn(ctx, "cannot list stores, retrying",
+			zap.Int("attempt", attempt),
+			zap.Int("attempts", attempts),
+			zap.Error(err),
+		)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// LatestAuthModel can be used as the authorization model ID, in
+// OpenFGAParams.AuthModelID or SetAuthModelID, to always use the latest
+// authorization model in the store. When this is the case, the
+// authorization model ID is omitted from requests, and OpenFGA resolves the
+// latest model for each request, so that models written after the client was
+// created are picked up without reconfiguring the client.
+const LatestAuthModel = ""
+
+// AuthModelID returns the currently configured authorization model ID, or
+// LatestAuthModel if the latest authorization model is used.
 func (c *Client) AuthModelID() string {
 	return c.authModelID
 }
 
 // SetAuthModelID sets the authorization model ID to be used by the client.
+// Use LatestAuthModel to always use the latest authorization model in the
+// store.
 func (c *Client) SetAuthModelID(authModelID string) {
 	c.authModelID = authModelID
 }
@@ -181,12 +825,114 @@ func (c *Client) SetStoreID(storeID string) {
 	c.storeID = storeID
 }
 
+// IsConfigured reports whether the client is configured with a store ID and
+// with an authorization model ID. Note that a client configured with a store
+// but without an authorization model ID is usable, and uses the latest
+// authorization model in the store (see LatestAuthModel).
+func (c *Client) IsConfigured() (hasStore bool, hasModel bool) {
+	return c.storeID != "", c.authModelID != LatestAuthModel
+}
+
+// Clone returns a new client sharing the underlying OpenFGA API client (and
+// therefore its HTTP transport and connection pool) with c, but whose store
+// ID and authorization model ID can be set independently. This is useful, for
+// instance, to create cheap per-tenant clients in services serving multiple
+// tenants, instead of calling SetStoreID or SetAuthModelID on a client shared
+// between goroutines.
+func (c *Client) Clone() *Client {
+	clone := *c
+	return &clone
+}
+
 // AddRelation adds the specified relation(s) between the objects & targets as
 // specified by the given tuple(s).
 func (c *Client) AddRelation(ctx context.Context, tuples ...Tuple) error {
 	return c.AddRemoveRelations(ctx, tuples, nil)
 }
 
+// AddRelationSafe behaves like AddRelation, but tolerates tuples that already
+// exist, for instance because they were written concurrently by another
+// client. OpenFGA rejects a write request as invalid if any of its tuples
+// already exists: when this happens, each tuple is read back to find out
+// which tuples are missing, and only those are written.
+//
+// Note that this costs an additional Read request per tuple when the write
+// is rejected. More recent OpenFGA servers support ignoring duplicate tuples
+// natively, but this is not exposed by the OpenFGA client used by this
+// library, so this method can be used with any server version.
+func (c *Client) AddRelationSafe(ctx context.Context, tuples ...Tuple) error {
+	_, err := c.addMissingRelations(ctx, tuples)
+	return err
+}
+
+// AddRelationIfAbsent behaves like AddRelationSafe for a single tuple, and
+// also reports whether the tuple was created by this call, or whether it
+// already existed. This is useful, for instance, to tell users whether access
+// was granted or whether they already had it.
+func (c *Client) AddRelationIfAbsent(ctx context.Context, tuple Tuple) (created bool, err error) {
+	n, err := c.addMissingRelations(ctx, []Tuple{tuple})
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// addMissingRelations adds the given tuples, ignoring the ones that already
+// exist, and returns the number of added tuples.
+func (c *Client) addMissingRelations(ctx context.Context, tuples []Tuple) (int, error) {
+	for {
+		err := c.AddRelation(ctx, tuples...)
+		if err == nil {
+			return len(tuples), nil
+		}
+		var validationErr openfga.FgaApiValidationError
+		if !errors.As(err, &validationErr) {
+			return 0, err
+		}
+		zapctx.Debug(ctx, "cannot add relations, checking for existing tuples", zap.Error(err))
+		var missing []Tuple
+		for _, t := range tuples {
+			query := Tuple{
+				Object:   t.Object,
+				Relation: t.Relation,
+				Target:   t.Target,
+			}
+			found, _, err := c.FindMatchingTuples(ctx, query, 0, "")
+			if err != nil {
+				return 0, err
+			}
+			if len(found) == 0 {
+				missing = append(missing, t)
+			}
+		}
+		// If no tuple already exists, the write was rejected for a
+		// different reason.
+		if len(missing) == len(tuples) {
+			return 0, err
+		}
+		if len(missing) == 0 {
+			return 0, nil
+		}
+		// Retry with the missing tuples only, which may in turn have been
+		// written concurrently in the meantime.
+		tuples = missing
+	}
+}
+
+// SetRelation grants or revokes the relation specified by the given tuple,
+// depending on grant: if grant is true, the tuple is added as done by
+// AddRelationSafe, otherwise it is removed. Both operations are idempotent:
+// granting a relation that already exists or revoking a relation that does
+// not exist is not an error. This is useful, for instance, to apply the state
+// of a toggle in a user interface.
+func (c *Client) SetRelation(ctx context.Context, tuple Tuple, grant bool) error {
+	if grant {
+		return c.AddRelationSafe(ctx, tuple)
+	}
+	_, err := c.removeExistingRelations(ctx, []Tuple{tuple})
+	return err
+}
+
 // CheckRelation checks whether the specified relation exists (either directly
 // or indirectly) between the object and the target specified by the tuple.
 //
@@ -194,9 +940,47 @@ func (c *Client) AddRelation(ctx context.Context, tuples ...Tuple) error {
 // check request with temporary, non-persistent relationship tuples that exist
 // solely within the scope of this specific check. Contextual tuples are not
 // written to the store but are taken into account for this particular check
-// request as if they were present in the store.
+// request as if they were present in the store. Contextual tuples may carry a
+// Condition, which allows checking whether access would be allowed if a
+// conditional relationship existed. Note that conditions are only supported
+// by OpenFGA 1.4.0 or later: older servers ignore the conditions of
+// contextual tuples, which would therefore be evaluated as unconditional
+// relationships, possibly granting access. As the OpenFGA API does not
+// expose the server version, an error is returned instead if any contextual
+// tuple has a condition but the authorization model used by the client does
+// not define any conditions, which is always the case with older servers.
+// The authorization model is only retrieved when a contextual tuple has a
+// condition, and the result is cached if the client is configured with an
+// authorization model ID.
+//
+// If a condition cannot be evaluated by the server, the returned error wraps
+// a *ConditionEvaluationError.
 func (c *Client) CheckRelation(ctx context.Context, tuple Tuple, contextualTuples ...Tuple) (bool, error) {
-	return c.checkRelation(ctx, tuple, false, contextualTuples...)
+	allowed, _, err := c.checkRelation(ctx, tuple, false, nil, contextualTuples...)
+	return allowed, err
+}
+
+// CheckRelationOptions holds optional parameters for the
+// CheckRelationWithOptions method.
+type CheckRelationOptions struct {
+	// ContextualTuples specifies temporary, non-persistent relationship
+	// tuples that are taken into account for this request as if they were
+	// present in the store.
+	ContextualTuples []Tuple
+	// Context specifies additional request context used to evaluate any
+	// conditions encountered while resolving the request.
+	Context map[string]any
+}
+
+// CheckRelationWithOptions behaves like CheckRelation, additionally allowing
+// a context to be specified to evaluate the conditions encountered while
+// checking the relation, e.g. the current time or the IP address of the
+// request when using attribute-based access control. The same context can be
+// passed to FindAccessibleObjectsByRelationWithOptions, so that the objects
+// returned are consistent with the checks.
+func (c *Client) CheckRelationWithOptions(ctx context.Context, tuple Tuple, opts CheckRelationOptions) (bool, error) {
+	allowed, _, err := c.checkRelation(ctx, tuple, false, opts.Context, opts.ContextualTuples...)
+	return allowed, err
 }
 
 // CheckRelationWithTracing verifies that the specified relation exists (either
@@ -209,38 +993,198 @@ func (c *Client) CheckRelation(ctx context.Context, tuple Tuple, contextualTuple
 // written to the store but are taken into account for this particular check
 // request as if they were present in the store.
 func (c *Client) CheckRelationWithTracing(ctx context.Context, tuple Tuple, contextualTuples ...Tuple) (bool, error) {
-	return c.checkRelation(ctx, tuple, true, contextualTuples...)
+	allowed, _, err := c.checkRelation(ctx, tuple, true, nil, contextualTuples...)
+	return allowed, err
+}
+
+// CheckRelationTrace behaves like CheckRelationWithTracing, but also returns
+// the resolution trace included in the check response, which describes how
+// the relation was resolved. This is useful for debugging why a relation
+// does or does not hold. The returned trace is empty if the server does not
+// include a resolution in its response.
+func (c *Client) CheckRelationTrace(ctx context.Context, tuple Tuple, contextualTuples ...Tuple) (bool, string, error) {
+	return c.checkRelation(ctx, tuple, true, nil, contextualTuples...)
+}
+
+// CheckRelations checks which of the given relations exist (either directly or
+// indirectly) between the object and the target, returning a map from each
+// relation to whether it holds. This is useful, for instance, to compute the
+// set of permissions a user has on a resource. The checks are executed
+// concurrently and, if any of them fails, the returned error joins the errors
+// of all the failed checks (see errors.Join).
+//
+// The contextualTuples are taken into account for each check, as described in
+// CheckRelation.
+func (c *Client) CheckRelations(ctx context.Context, object Entity, relations []Relation, target Entity, contextualTuples ...Tuple) (map[Relation]bool, error) {
+	type checkResult struct {
+		allowed bool
+		err     error
+	}
+	results := make([]checkResult, len(relations))
+	var wg sync.WaitGroup
+	for i, relation := range relations {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tuple := Tuple{Object: &object, Relation: relation, Target: &target}
+			allowed, _, err := c.checkRelation(ctx, tuple, false, nil, contextualTuples...)
+			results[i] = checkResult{allowed: allowed, err: err}
+		}()
+	}
+	wg.Wait()
+
+	allowed := make(map[Relation]bool, len(relations))
+	var errs []error
+	for i, result := range results {
+		if result.err != nil {
+			errs = append(errs, result.err)
+			continue
+		}
+		allowed[relations[i]] = result.allowed
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return allowed, nil
+}
+
+const (
+	// waitForRelationInitialInterval and waitForRelationMaxInterval specify
+	// the bounds of the exponential backoff used by WaitForRelation between
+	// check requests.
+	waitForRelationInitialInterval = 50 * time.Millisecond
+	waitForRelationMaxInterval     = 2 * time.Second
+)
+
+// WaitForRelation waits until checking the relation specified by the given
+// tuple returns the expected result, for instance to wait for a relation just
+// added (or removed) to be visible to checks performed against a server
+// backed by read replicas. The relation is checked repeatedly, waiting an
+// exponentially increasing interval between checks, until the result matches
+// expected or the timeout elapses, in which case an error is returned. Errors
+// returned by the check requests are returned immediately.
+func (c *Client) WaitForRelation(ctx context.Context, tuple Tuple, expected bool, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	interval := waitForRelationInitialInterval
+	for {
+		allowed, _, err := c.checkRelation(ctx, tuple, false, nil)
+		if err != nil {
+			return err
+		}
+		if allowed == expected {
+			return nil
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("timed out waiting for relation %s to be %v", tuple, expected)
+		}
+		zapctx.Debug(ctx, "relation does not match, retrying",
+			zap.Stringer("tuple", tuple),
+			zap.Bool("expected", expected),
+			zap.Duration("interval", interval),
+		)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(min(interval, remaining)):
+		}
+		interval = min(2*interval, waitForRelationMaxInterval)
+	}
+}
+
+// ConditionEvaluationError is returned, wrapped, by the methods checking
+// relations when the server cannot evaluate a condition involved in the
+// check, for instance because some of its parameters are missing from the
+// condition context. In this case, the relation is neither known to be
+// allowed nor denied, and callers can use errors.As to decide whether to
+// fail open or closed. Other errors, such as the server being unreachable or
+// failing, are returned as generic errors, while a denied relation is never
+// reported as an error.
+type ConditionEvaluationError struct {
+	// Message holds the error message returned by the server.
+	Message string
+
+	err error
+}
+
+// Error implements the error interface.
+func (e *ConditionEvaluationError) Error() string {
+	return "cannot evaluate condition: " + e.Message
+}
+
+// Unwrap returns the underlying OpenFGA error.
+func (e *ConditionEvaluationError) Unwrap() error {
+	return e.err
+}
+
+// asConditionEvaluationError returns a ConditionEvaluationError if the given
+// error returned by a Check request reports that a condition could not be
+// evaluated, or nil otherwise. OpenFGA reports these failures as validation
+// errors whose message starts with "failed to evaluate relationship
+// condition".
+func asConditionEvaluationError(err error) *ConditionEvaluationError {
+	var validationErr openfga.FgaApiValidationError
+	if !errors.As(err, &validationErr) || validationErr.ResponseCode() != openfga.ERRORCODE_VALIDATION_ERROR {
+		return nil
+	}
+	model, ok := validationErr.Model().(openfga.ValidationErrorMessageResponse)
+	if !ok || !strings.Contains(model.GetMessage(), "failed to evaluate relationship condition") {
+		return nil
+	}
+	return &ConditionEvaluationError{
+		Message: model.GetMessage(),
+		err:     err,
+	}
 }
 
-// checkRelation internal implementation for check relation procedure.
-func (c *Client) checkRelation(ctx context.Context, tuple Tuple, trace bool, contextualTuples ...Tuple) (bool, error) {
+// checkRelation internal implementation for check relation procedure. The
+// conditionContext, if not nil, is used to evaluate any conditions
+// encountered while resolving the check.
+func (c *Client) checkRelation(ctx context.Context, tuple Tuple, trace bool, conditionContext map[string]any, contextualTuples ...Tuple) (bool, string, error) {
 	zapctx.Debug(
 		ctx,
 		"check request internal",
-		zap.String("tuple object", tuple.Object.String()),
-		zap.String("tuple relation", tuple.Relation.String()),
-		zap.String("tuple target object", tuple.Target.String()),
+		zap.Stringer("tuple", tuple),
 		zap.Bool("trace", trace),
 		zap.Int("contextual tuples", len(contextualTuples)),
 	)
+	// Contextual tuples may contain sensitive information, so they are only
+	// logged when debugging is explicitly enabled.
+	if c.debug && len(contextualTuples) > 0 {
+		zapctx.Debug(ctx, "check request contextual tuples", zap.Stringers("tuples", contextualTuples))
+	}
+	if err := c.checkContextualConditionsSupported(ctx, contextualTuples); err != nil {
+		return false, "", fmt.Errorf("cannot check relation: %w", err)
+	}
 	cr := openfga.NewCheckRequest(*tuple.ToOpenFGACheckRequestTupleKey())
-	cr.SetAuthorizationModelId(c.authModelID)
+	if c.authModelID != LatestAuthModel {
+		cr.SetAuthorizationModelId(c.authModelID)
+	}
 
 	if len(contextualTuples) > 0 {
-		keys := tuplesToOpenFGATupleKeys(contextualTuples)
+		keys := contextualTuplesToOpenFGATupleKeys(contextualTuples)
 		cr.SetContextualTuples(*openfga.NewContextualTupleKeys(keys))
 	}
+	if conditionContext != nil {
+		cr.SetContext(conditionContext)
+	}
 
 	cr.SetTrace(trace)
 
 	checkResp, httpResp, err := c.api.Check(ctx, c.storeID).Body(*cr).Execute()
 	if err != nil {
 		zapctx.Error(ctx, fmt.Sprintf("cannot execute Check request: %v", err))
-		return false, fmt.Errorf("cannot check relation: %v", err)
+		if evalErr := asConditionEvaluationError(err); evalErr != nil {
+			return false, "", fmt.Errorf("cannot check relation: %w", evalErr)
+		}
+		return false, "", fmt.Errorf("cannot check relation: %w", err)
 	}
 	allowed := checkResp.GetAllowed()
 	zapctx.Debug(ctx, "check request internal resp code", zap.Int("code", httpResp.StatusCode), zap.Bool("allowed", allowed))
-	return allowed, nil
+	if c.onCheck != nil {
+		c.onCheck(tuple, allowed)
+	}
+	return allowed, checkResp.GetResolution(), nil
 }
 
 // RemoveRelation removes the specified relation(s) between the objects &
@@ -249,12 +1193,103 @@ func (c *Client) RemoveRelation(ctx context.Context, tuples ...Tuple) error {
 	return c.AddRemoveRelations(ctx, nil, tuples)
 }
 
+// RemoveRelationBatched removes the given tuples in batches of at most
+// batchSize tuples, each one sent in a separate write request, so that large
+// cleanup operations are not rejected because of the maximum number of tuples
+// per write request enforced by OpenFGA. If batchSize is not positive,
+// batches of 100 tuples are used.
+//
+// Tuples that do not exist are tolerated: OpenFGA rejects a write request as
+// invalid if any of the tuples to delete is missing, in which case the tuples
+// in the batch are read back and only the existing ones are removed, as done
+// by AddRelationSafe for existing tuples. The number of removed tuples is
+// returned, even if an error occurs. Batches are removed independently: when
+// a batch fails, the remaining batches are still removed, and the returned
+// error joins the errors of all the failed batches (see errors.Join), which
+// can be inspected individually using errors.As.
+func (c *Client) RemoveRelationBatched(ctx context.Context, tuples []Tuple, batchSize int) (int, error) {
+	if batchSize <= 0 {
+		batchSize = maxTuplesPerWrite
+	}
+	removed := 0
+	var errs []error
+	for i := 0; len(tuples) > 0; i++ {
+		batch := tuples[:min(len(tuples), batchSize)]
+		n, err := c.removeExistingRelations(ctx, batch)
+		removed += n
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cannot remove batch %d: %w", i, err))
+		}
+		tuples = tuples[len(batch):]
+	}
+	zapctx.Debug(ctx, "removed relations in batches", zap.Int("count", removed), zap.Int("failed batches", len(errs)))
+	return removed, errors.Join(errs...)
+}
+
+// removeExistingRelations removes the given tuples, ignoring the ones that do
+// not exist, and returns the number of removed tuples.
+func (c *Client) removeExistingRelations(ctx context.Context, tuples []Tuple) (int, error) {
+	for {
+		err := c.RemoveRelation(ctx, tuples...)
+		if err == nil {
+			return len(tuples), nil
+		}
+		var validationErr openfga.FgaApiValidationError
+		if !errors.As(err, &validationErr) {
+			return 0, err
+		}
+		zapctx.Debug(ctx, "cannot remove relations, checking for missing tuples", zap.Error(err))
+		var existing []Tuple
+		for _, t := range tuples {
+			query := Tuple{
+				Object:   t.Object,
+				Relation: t.Relation,
+				Target:   t.Target,
+			}
+			found, _, err := c.FindMatchingTuples(ctx, query, 0, "")
+			if err != nil {
+				return 0, err
+			}
+			if len(found) > 0 {
+				existing = append(existing, t)
+			}
+		}
+		// If all tuples exist, the write was rejected for a different
+		// reason.
+		if len(existing) == len(tuples) {
+			return 0, err
+		}
+		if len(existing) == 0 {
+			return 0, nil
+		}
+		// Retry with the existing tuples only, which may in turn have been
+		// removed concurrently in the meantime.
+		tuples = existing
+	}
+}
+
 // AddRemoveRelations adds and removes the specified relation tuples in a single
 // atomic write operation. If you want to solely add relations or solely remove
 // relations, consider using the AddRelation or RemoveRelation methods instead.
+// If an OnWrite callback was configured, it is called once the write succeeds.
+//
+// The conditions of the tuples to add are stored along with them, while the
+// conditions of the tuples to remove are ignored: OpenFGA identifies tuples by
+// object, relation and target only, so a stored tuple is removed regardless
+// of its condition.
 func (c *Client) AddRemoveRelations(ctx context.Context, addTuples, removeTuples []Tuple) error {
+	if c.readOnly {
+		return fmt.Errorf("cannot add or remove relations: %w", ErrReadOnly)
+	}
+	if c.validateConditions {
+		if err := c.validateConditionsAgainstModel(ctx, addTuples); err != nil {
+			return fmt.Errorf("cannot add or remove relations: %w", err)
+		}
+	}
 	wr := openfga.NewWriteRequest()
-	wr.SetAuthorizationModelId(c.authModelID)
+	if c.authModelID != LatestAuthModel {
+		wr.SetAuthorizationModelId(c.authModelID)
+	}
 
 	if len(addTuples) > 0 {
 		addTupleKeys := tuplesToOpenFGATupleKeys(addTuples)
@@ -267,18 +1302,123 @@ func (c *Client) AddRemoveRelations(ctx context.Context, addTuples, removeTuples
 	_, _, err := c.api.Write(ctx, c.storeID).Body(*wr).Execute()
 	if err != nil {
 		zapctx.Error(ctx, fmt.Sprintf("cannot execute Write request: %v", err))
-		return fmt.Errorf("cannot add or remove relations: %v", err)
+		return fmt.Errorf("cannot add or remove relations: %w", err)
+	}
+	if c.onWrite != nil {
+		c.onWrite(addTuples, removeTuples)
+	}
+	return nil
+}
+
+// validateConditionsAgainstModel validates the conditions of the given tuples
+// against the current authorization model, which is only retrieved if any
+// tuple has a condition.
+func (c *Client) validateConditionsAgainstModel(ctx context.Context, tuples []Tuple) error {
+	var model *openfga.AuthorizationModel
+	var errs []error
+	for i, t := range tuples {
+		if t.Condition == nil {
+			continue
+		}
+		if model == nil {
+			m, err := c.currentAuthModel(ctx)
+			if err != nil {
+				return fmt.Errorf("cannot retrieve the authorization model to validate conditions: %w", err)
+			}
+			model = &m
+		}
+		if err := validateConditionAgainstModel(*t.Condition, model); err != nil {
+			errs = append(errs, fmt.Errorf("invalid tuple %d (%s): %v", i, t, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkContextualConditionsSupported returns an error if any of the given
+// contextual tuples has a condition but the authorization model used by the
+// client does not define any conditions. This is always the case with
+// OpenFGA servers older than 1.4.0, which would silently ignore the
+// conditions of contextual tuples.
+func (c *Client) checkContextualConditionsSupported(ctx context.Context, tuples []Tuple) error {
+	i := slices.IndexFunc(tuples, func(t Tuple) bool {
+		return t.Condition != nil
+	})
+	if i == -1 {
+		return nil
+	}
+	hasConditions, err := c.modelHasConditions(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot retrieve the authorization model to validate conditions: %w", err)
+	}
+	if !hasConditions {
+		return fmt.Errorf("invalid contextual tuple %d (%s): the authorization model does not define any conditions (conditions require OpenFGA >= %s)", i, tuples[i], conditionsMinServerVersion)
 	}
 	return nil
 }
 
+// modelHasConditions reports whether the authorization model used by the
+// client defines any conditions. As authorization models cannot be changed,
+// the result is cached unless the client uses the latest model in the store.
+func (c *Client) modelHasConditions(ctx context.Context) (bool, error) {
+	key := c.storeID + "/" + c.authModelID
+	cacheable := c.authModelID != LatestAuthModel
+	if cacheable {
+		if hasConditions, ok := c.modelConditions.get(key); ok {
+			return hasConditions, nil
+		}
+	}
+	model, err := c.currentAuthModel(ctx)
+	if err != nil {
+		return false, err
+	}
+	hasConditions := len(model.GetConditions()) > 0
+	if cacheable {
+		c.modelConditions.set(key, hasConditions)
+	}
+	return hasConditions, nil
+}
+
+// modelConditionsCache records whether authorization models, identified by
+// their store and model IDs, define any conditions. It is shared by clones of
+// a client.
+type modelConditionsCache struct {
+	mu     sync.Mutex
+	models map[string]bool
+}
+
+// newModelConditionsCache returns an empty modelConditionsCache.
+func newModelConditionsCache() *modelConditionsCache {
+	return &modelConditionsCache{models: make(map[string]bool)}
+}
+
+// get returns whether the model with the given key defines any conditions,
+// and whether the model is in the cache.
+func (m *modelConditionsCache) get(key string) (hasConditions, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hasConditions, ok = m.models[key]
+	return hasConditions, ok
+}
+
+// set records whether the model with the given key defines any conditions.
+func (m *modelConditionsCache) set(key string, hasConditions bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.models[key] = hasConditions
+}
+
 // CreateStore creates a new store on the openFGA instance and returns its ID.
+// Note that the OpenFGA API does not allow updating a store once created, so
+// its name cannot be changed later.
 func (c *Client) CreateStore(ctx context.Context, name string) (string, error) {
+	if c.readOnly {
+		return "", fmt.Errorf("cannot create store: %w", ErrReadOnly)
+	}
 	csr := openfga.NewCreateStoreRequest(name)
 	resp, _, err := c.api.CreateStore(ctx).Body(*csr).Execute()
 	if err != nil {
 		zapctx.Error(ctx, fmt.Sprintf("cannot execute CreateStore request: %v", err))
-		return "", fmt.Errorf("cannot create store: %v", err)
+		return "", fmt.Errorf("cannot create store: %w", err)
 	}
 	return resp.GetId(), nil
 }
@@ -300,7 +1440,7 @@ func (c *Client) ListStores(ctx context.Context, pageSize int32, continuationTok
 	resp, _, err := lsr.Execute()
 	if err != nil {
 		zapctx.Error(ctx, fmt.Sprintf("cannot execute ListStores request: %v", err))
-		return openfga.ListStoresResponse{}, fmt.Errorf("cannot list stores: %v", err)
+		return openfga.ListStoresResponse{}, fmt.Errorf("cannot list stores: %w", err)
 	}
 	return resp, nil
 }
@@ -326,14 +1466,54 @@ func (c *Client) ReadChanges(ctx context.Context, entityType string, pageSize in
 	resp, _, err := rcr.Execute()
 	if err != nil {
 		zapctx.Error(ctx, fmt.Sprintf("cannot execute ReadChanges request: %v", err))
-		return openfga.ReadChangesResponse{}, fmt.Errorf("cannot read changes: %v", err)
+		return openfga.ReadChangesResponse{}, fmt.Errorf("cannot read changes: %w", err)
 	}
 	return resp, nil
 }
 
+// ReadChangesForKind behaves like ReadChanges, but accepts the type of the
+// entities to restrict the changes to as a Kind.
+func (c *Client) ReadChangesForKind(ctx context.Context, kind Kind, pageSize int32, continuationToken string) (openfga.ReadChangesResponse, error) {
+	return c.ReadChanges(ctx, kind.String(), pageSize, continuationToken)
+}
+
+// ReadChangesTyped behaves like ReadChanges, but returns the tuple changes
+// and the continuation token separately, with the token as a plain string.
+// The returned token is empty if the response does not include one.
+func (c *Client) ReadChangesTyped(ctx context.Context, entityType string, pageSize int32, continuationToken string) ([]openfga.TupleChange, string, error) {
+	resp, err := c.ReadChanges(ctx, entityType, pageSize, continuationToken)
+	if err != nil {
+		return nil, "", err
+	}
+	return resp.GetChanges(), resp.GetContinuationToken(), nil
+}
+
+// supportedSchemaVersions holds the authorization model schema versions
+// accepted by AuthModelFromJSON.
+var supportedSchemaVersions = []string{"1.1", "1.2"}
+
 // AuthModelFromJSON converts the input json representation of an authorization
 // model into an [openfga.AuthorizationModel] that can be used with the API.
+// An error is returned if the model does not specify one of the supported
+// schema versions (1.1 and 1.2): use AuthModelFromJSONWithOptions to skip
+// this check.
 func AuthModelFromJSON(data []byte) (*openfga.AuthorizationModel, error) {
+	return AuthModelFromJSONWithOptions(data, AuthModelFromJSONOptions{})
+}
+
+// AuthModelFromJSONOptions holds optional parameters for the
+// AuthModelFromJSONWithOptions function.
+type AuthModelFromJSONOptions struct {
+	// SkipSchemaVersionCheck disables the validation of the schema version,
+	// so that models using schema versions not yet known to this package can
+	// be parsed. The schema version is then validated by the OpenFGA server
+	// when the model is created.
+	SkipSchemaVersionCheck bool
+}
+
+// AuthModelFromJSONWithOptions behaves like AuthModelFromJSON, accepting
+// additional options.
+func AuthModelFromJSONWithOptions(data []byte, opts AuthModelFromJSONOptions) (*openfga.AuthorizationModel, error) {
 	var parsed openfga.AuthorizationModel
 	if err := json.Unmarshal(data, &parsed); err != nil {
 		return nil, fmt.Errorf("cannot unmarshal JSON auth model: %v", err)
@@ -343,22 +1523,209 @@ func AuthModelFromJSON(data []byte) (*openfga.AuthorizationModel, error) {
 		return nil, fmt.Errorf(`"type_definitions" field not found`)
 	}
 
+	if !opts.SkipSchemaVersionCheck {
+		if parsed.SchemaVersion == "" {
+			return nil, fmt.Errorf(`"schema_version" field not found`)
+		}
+		if !slices.Contains(supportedSchemaVersions, parsed.SchemaVersion) {
+			return nil, fmt.Errorf("unsupported schema version %q, supported versions are %s", parsed.SchemaVersion, strings.Join(supportedSchemaVersions, ", "))
+		}
+	}
+
 	return &parsed, nil
 }
 
-// CreateAuthModel creates a new authorization model as per the provided type
-// definitions and schemaVersion and returns its ID. The [AuthModelFromJSON]
-// function can be used to convert an authorization model from json to the
-// slice of type definitions required by this method.
-func (c *Client) CreateAuthModel(ctx context.Context, authModel *openfga.AuthorizationModel) (string, error) {
-	ar := openfga.NewWriteAuthorizationModelRequest(authModel.TypeDefinitions, authModel.SchemaVersion)
-	ar.SetSchemaVersion(authModel.SchemaVersion)
-	resp, _, err := c.api.WriteAuthorizationModel(ctx, c.storeID).Body(*ar).Execute()
-	if err != nil {
-		zapctx.Error(ctx, fmt.Sprintf("cannot execute WriteAuthorizationModel request: %v", err))
-		return "", fmt.Errorf("cannot create auth model: %v", err)
+// CreateAuthModel creates a new authorization model as per the provided type
+// definitions and schemaVersion and returns its ID. The [AuthModelFromJSON]
+// function can be used to convert an authorization model from json to the
+// slice of type definitions required by this method.
+//
+// Note that authorization models are immutable, and OpenFGA does not provide
+// an API to delete them, so models accumulate in the store over time. Old
+// models are harmless, as only the model used by the client (or the latest
+// one) is taken into account. If they must be discarded anyway, create a new
+// store, write the models to keep to it, and copy the tuples using
+// ExportTuples and ImportTuples.
+//
+// The conditions defined in the model, if any, are created along with it.
+func (c *Client) CreateAuthModel(ctx context.Context, authModel *openfga.AuthorizationModel) (string, error) {
+	if c.readOnly {
+		return "", fmt.Errorf("cannot create auth model: %w", ErrReadOnly)
+	}
+	ar := openfga.NewWriteAuthorizationModelRequest(authModel.TypeDefinitions, authModel.SchemaVersion)
+	ar.SetSchemaVersion(authModel.SchemaVersion)
+	ar.Conditions = authModel.Conditions
+	resp, _, err := c.api.WriteAuthorizationModel(ctx, c.storeID).Body(*ar).Execute()
+	if err != nil {
+		zapctx.Error(ctx, fmt.Sprintf("cannot execute WriteAuthorizationModel request: %v", err))
+		return "", fmt.Errorf("cannot create auth model: %w", err)
+	}
+	return resp.GetAuthorizationModelId(), nil
+}
+
+// CreateAuthModelFromFile creates a new authorization model as defined in the
+// file at the given path and returns its ID. The file may contain either the
+// JSON representation of the model or its definition in the OpenFGA modeling
+// language (DSL): files with a ".json" extension are parsed as JSON, files
+// with a ".fga" or ".openfga" extension are parsed as DSL, and the format of
+// any other file is detected from its content.
+func (c *Client) CreateAuthModelFromFile(ctx context.Context, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("cannot read auth model file: %v", err)
+	}
+	var authModel *openfga.AuthorizationModel
+	switch ext := filepath.Ext(path); {
+	case ext == ".json":
+		authModel, err = AuthModelFromJSON(data)
+	case ext == ".fga" || ext == ".openfga":
+		authModel, err = AuthModelFromDSL(data)
+	case bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")):
+		authModel, err = AuthModelFromJSON(data)
+	default:
+		authModel, err = AuthModelFromDSL(data)
+	}
+	if err != nil {
+		return "", fmt.Errorf("cannot parse auth model file %s: %v", path, err)
+	}
+	return c.CreateAuthModel(ctx, authModel)
+}
+
+// CreateAuthModelFull creates a new authorization model as per the provided
+// type definitions and schemaVersion and returns the model as stored by the
+// openFGA instance, including any server-assigned fields such as its ID.
+func (c *Client) CreateAuthModelFull(ctx context.Context, authModel *openfga.AuthorizationModel) (openfga.AuthorizationModel, error) {
+	authModelID, err := c.CreateAuthModel(ctx, authModel)
+	if err != nil {
+		return openfga.AuthorizationModel{}, err
+	}
+	return c.GetAuthModel(ctx, authModelID)
+}
+
+// CreateAuthModelValidated creates a new authorization model, writes the
+// provided assertions against it and then verifies each stored assertion by
+// running it as a Check request against the newly created model. If any of
+// the assertions do not hold, an error listing them is returned along with
+// the ID of the created model. Note that OpenFGA does not allow deleting
+// authorization models, so the model remains in the store even when the
+// validation fails; callers should simply not start using it. The auth model
+// ID configured on the client is not modified by this method.
+func (c *Client) CreateAuthModelValidated(ctx context.Context, authModel *openfga.AuthorizationModel, assertions []openfga.Assertion) (string, error) {
+	authModelID, err := c.CreateAuthModel(ctx, authModel)
+	if err != nil {
+		return "", err
+	}
+
+	war := openfga.NewWriteAssertionsRequest(assertions)
+	_, err = c.api.WriteAssertions(ctx, c.storeID, authModelID).Body(*war).Execute()
+	if err != nil {
+		zapctx.Error(ctx, fmt.Sprintf("cannot execute WriteAssertions request: %v", err))
+		return authModelID, fmt.Errorf("cannot write assertions: %w", err)
+	}
+
+	resp, _, err := c.api.ReadAssertions(ctx, c.storeID, authModelID).Execute()
+	if err != nil {
+		zapctx.Error(ctx, fmt.Sprintf("cannot execute ReadAssertions request: %v", err))
+		return authModelID, fmt.Errorf("cannot read assertions: %w", err)
+	}
+
+	var failed []string
+	for _, assertion := range resp.GetAssertions() {
+		tk := assertion.GetTupleKey()
+		cr := openfga.NewCheckRequest(*openfga.NewCheckRequestTupleKey(tk.GetUser(), tk.GetRelation(), tk.GetObject()))
+		cr.SetAuthorizationModelId(authModelID)
+		checkResp, _, err := c.api.Check(ctx, c.storeID).Body(*cr).Execute()
+		if err != nil {
+			zapctx.Error(ctx, fmt.Sprintf("cannot execute Check request: %v", err))
+			return authModelID, fmt.Errorf("cannot check assertion: %w", err)
+		}
+		if checkResp.GetAllowed() != assertion.GetExpectation() {
+			failed = append(failed, fmt.Sprintf("(%s, %s, %s) expected %t", tk.GetUser(), tk.GetRelation(), tk.GetObject(), assertion.GetExpectation()))
+		}
+	}
+	if len(failed) > 0 {
+		return authModelID, fmt.Errorf("assertions do not hold for auth model %s: %s", authModelID, strings.Join(failed, "; "))
+	}
+	return authModelID, nil
+}
+
+// WriteAssertionsFromFile reads the assertions in the file at the given path
+// and writes them for the authorization model with the given ID, replacing
+// any assertions previously written for the model. Assertions are test cases
+// for the authorization model and can be verified by creating the model with
+// CreateAuthModelValidated.
+//
+// The file must contain one JSON object per line (NDJSON), each defining an
+// assertion as a tuple along with the expected result of checking it, e.g.:
+//
+//	{"tuple": {"user": "user:alice", "relation": "viewer", "object": "document:1"}, "expected": true}
+//	{"tuple": {"user": "user:bob", "relation": "viewer", "object": "document:1"}, "expected": false}
+//
+// Empty lines are ignored.
+func (c *Client) WriteAssertionsFromFile(ctx context.Context, authModelID, path string) error {
+	if c.readOnly {
+		return fmt.Errorf("cannot write assertions: %w", ErrReadOnly)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("cannot read assertions file: %v", err)
+	}
+	assertions, err := parseAssertions(data)
+	if err != nil {
+		return fmt.Errorf("cannot parse assertions file %s: %v", path, err)
+	}
+	war := openfga.NewWriteAssertionsRequest(assertions)
+	_, err = c.api.WriteAssertions(ctx, c.storeID, authModelID).Body(*war).Execute()
+	if err != nil {
+		zapctx.Error(ctx, fmt.Sprintf("cannot execute WriteAssertions request: %v", err))
+		return fmt.Errorf("cannot write assertions: %w", err)
+	}
+	return nil
+}
+
+// fileAssertion is the JSON representation of an assertion in an assertions
+// file. See WriteAssertionsFromFile for more information.
+type fileAssertion struct {
+	Tuple struct {
+		User     string `json:"user"`
+		Relation string `json:"relation"`
+		Object   string `json:"object"`
+	} `json:"tuple"`
+	Expected *bool `json:"expected"`
+}
+
+// parseAssertions parses the given NDJSON encoded assertions.
+func parseAssertions(data []byte) ([]openfga.Assertion, error) {
+	assertions := []openfga.Assertion{}
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var a fileAssertion
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&a); err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		if _, err := ParseEntity(a.Tuple.User); err != nil {
+			return nil, fmt.Errorf("line %d: invalid tuple user: %v", i+1, err)
+		}
+		if a.Tuple.Relation == "" {
+			return nil, fmt.Errorf("line %d: missing tuple relation", i+1)
+		}
+		if _, err := ParseEntity(a.Tuple.Object); err != nil {
+			return nil, fmt.Errorf("line %d: invalid tuple object: %v", i+1, err)
+		}
+		if a.Expected == nil {
+			return nil, fmt.Errorf("line %d: missing expected result", i+1)
+		}
+		assertions = append(assertions, *openfga.NewAssertion(
+			*openfga.NewAssertionTupleKey(a.Tuple.Object, a.Tuple.Relation, a.Tuple.User),
+			*a.Expected,
+		))
 	}
-	return resp.GetAuthorizationModelId(), nil
+	return assertions, nil
 }
 
 // ListAuthModels returns the list of authorization models present on the
@@ -376,28 +1743,200 @@ func (c *Client) ListAuthModels(ctx context.Context, pageSize int32, continuatio
 	resp, _, err := rar.Execute()
 	if err != nil {
 		zapctx.Error(ctx, fmt.Sprintf("cannot execute ReadAuthorizationModels request: %v", err))
-		return openfga.ReadAuthorizationModelsResponse{}, fmt.Errorf("cannot list authorization models: %v", err)
+		return openfga.ReadAuthorizationModelsResponse{}, fmt.Errorf("cannot list authorization models: %w", err)
 	}
 	return resp, nil
 }
 
+// ListAllAuthModels returns all the authorization models present in the store,
+// from the newest to the oldest, following continuation tokens internally.
+func (c *Client) ListAllAuthModels(ctx context.Context) ([]openfga.AuthorizationModel, error) {
+	models := []openfga.AuthorizationModel{}
+	continuationToken := ""
+	for {
+		resp, err := c.ListAuthModels(ctx, 0, continuationToken)
+		if err != nil {
+			return nil, err
+		}
+		models = append(models, resp.GetAuthorizationModels()...)
+		token := resp.GetContinuationToken()
+		if token == "" || token == continuationToken {
+			return models, nil
+		}
+		continuationToken = token
+	}
+}
+
+// FindAuthModelsBySchemaVersion returns all the authorization models in the
+// store that use the given schema version (e.g. "1.1"), from the newest to the
+// oldest. Note that the OpenFGA API does not allow filtering models by schema
+// version, so this method fetches all the models in the store (see
+// ListAllAuthModels) and filters them.
+func (c *Client) FindAuthModelsBySchemaVersion(ctx context.Context, schemaVersion string) ([]openfga.AuthorizationModel, error) {
+	models, err := c.ListAllAuthModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	matching := []openfga.AuthorizationModel{}
+	for _, model := range models {
+		if model.SchemaVersion == schemaVersion {
+			matching = append(matching, model)
+		}
+	}
+	return matching, nil
+}
+
+// StoreInfo holds the metadata of a store, as returned by Client.StoreInfo.
+type StoreInfo struct {
+	// ID and Name hold the ID and the name of the store.
+	ID   string
+	Name string
+	// CreatedAt and UpdatedAt hold the time at which the store was created
+	// and last updated.
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// StoreInfo returns the metadata of the store configured for the client.
+//
+// Note that UpdatedAt only changes when the store itself is updated, and not
+// when authorization models or tuples are written, so it cannot be used to
+// detect model changes. To cheaply find out whether a new authorization model
+// was written, e.g. to invalidate a locally cached model, compare the ID of
+// the latest model, as returned by ListAuthModels(ctx, 1, ""), with the ID of
+// the cached one.
+func (c *Client) StoreInfo(ctx context.Context) (StoreInfo, error) {
+	store, _, err := c.api.GetStore(ctx, c.storeID).Execute()
+	if err != nil {
+		zapctx.Error(ctx, fmt.Sprintf("cannot execute GetStore request: %v", err))
+		return StoreInfo{}, fmt.Errorf("cannot retrieve store: %w", err)
+	}
+	return StoreInfo{
+		ID:        store.GetId(),
+		Name:      store.GetName(),
+		CreatedAt: store.GetCreatedAt(),
+		UpdatedAt: store.GetUpdatedAt(),
+	}, nil
+}
+
+// StoreSummary holds a summary of the content of a store, as returned by
+// Client.StoreSummary.
+type StoreSummary struct {
+	// StoreName holds the name of the store.
+	StoreName string
+	// AuthModelCount holds the number of authorization models in the store.
+	AuthModelCount int
+	// LatestAuthModelID and LatestSchemaVersion hold the ID and schema
+	// version of the most recent authorization model in the store. They are
+	// empty if the store has no authorization models.
+	LatestAuthModelID   string
+	LatestSchemaVersion string
+}
+
+// StoreSummary returns a summary of the store configured in the client,
+// including its name, the number of authorization models it holds and the ID
+// and schema version of the latest one. Note that all the authorization models
+// in the store are listed in order to count them.
+func (c *Client) StoreSummary(ctx context.Context) (StoreSummary, error) {
+	store, _, err := c.api.GetStore(ctx, c.storeID).Execute()
+	if err != nil {
+		zapctx.Error(ctx, fmt.Sprintf("cannot execute GetStore request: %v", err))
+		return StoreSummary{}, fmt.Errorf("cannot retrieve store: %w", err)
+	}
+	models, err := c.ListAllAuthModels(ctx)
+	if err != nil {
+		return StoreSummary{}, err
+	}
+	summary := StoreSummary{
+		StoreName:      store.GetName(),
+		AuthModelCount: len(models),
+	}
+	// Authorization models are listed from the newest to the oldest.
+	if len(models) > 0 {
+		summary.LatestAuthModelID = models[0].Id
+		summary.LatestSchemaVersion = models[0].SchemaVersion
+	}
+	return summary, nil
+}
+
 // GetAuthModel fetches an authorization model by ID from the openFGA instance.
+// The model is returned as is, including the metadata of modular models: the
+// module and source file defining each type and relation are available in
+// the Module and SourceInfo fields of openfga.Metadata and
+// openfga.RelationMetadata.
 func (c *Client) GetAuthModel(ctx context.Context, ID string) (openfga.AuthorizationModel, error) {
 	resp, _, err := c.api.ReadAuthorizationModel(ctx, c.storeID, ID).Execute()
 	if err != nil {
 		zapctx.Error(ctx, fmt.Sprintf("cannot execute ReadAuthorizationModel request: %v", err))
-		return openfga.AuthorizationModel{}, fmt.Errorf("cannot list authorization models: %v", err)
+		return openfga.AuthorizationModel{}, fmt.Errorf("cannot list authorization models: %w", err)
 	}
 	return resp.GetAuthorizationModel(), nil
 }
 
+// GetAuthModelAsDSL retrieves the authorization model with the given ID and
+// returns it rendered using the OpenFGA modeling language. See AuthModelToDSL
+// for more information.
+func (c *Client) GetAuthModelAsDSL(ctx context.Context, id string) (string, error) {
+	model, err := c.GetAuthModel(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	dsl, err := AuthModelToDSL(model)
+	if err != nil {
+		return "", fmt.Errorf("cannot render authorization model %s: %v", id, err)
+	}
+	return dsl, nil
+}
+
+// AssertModelHasRelations verifies that the authorization model used by the
+// client defines all the given types and relations, returning an error listing
+// the missing ones otherwise. A type mapped to no relations is only required
+// to be defined. If no authorization model ID is configured on the client,
+// the latest authorization model in the store is checked.
+//
+// This method can be called when a service starts, in order to fail fast
+// when the deployed authorization model does not match the one expected by
+// the service.
+func (c *Client) AssertModelHasRelations(ctx context.Context, required map[Kind][]Relation) error {
+	model, err := c.currentAuthModel(ctx)
+	if err != nil {
+		return err
+	}
+
+	relations := make(map[Kind]map[string]openfga.Userset, len(model.GetTypeDefinitions()))
+	for _, td := range model.GetTypeDefinitions() {
+		relations[Kind(td.GetType())] = td.GetRelations()
+	}
+	var missing []string
+	for kind, rels := range required {
+		defined, ok := relations[kind]
+		if !ok {
+			missing = append(missing, "type "+kind.String())
+			continue
+		}
+		for _, rel := range rels {
+			if _, ok := defined[rel.String()]; !ok {
+				missing = append(missing, "relation "+kind.String()+"#"+rel.String())
+			}
+		}
+	}
+	if len(missing) > 0 {
+		slices.Sort(missing)
+		return fmt.Errorf("authorization model %s is missing %s", model.GetId(), strings.Join(missing, ", "))
+	}
+	return nil
+}
+
 // validateTupleForFindMatchingTuples validates that the input tuples to the
 // FindMatchingTuples method complies with the API requirements.
 func validateTupleForFindMatchingTuples(tuple Tuple) error {
-	if tuple.Target.Kind == "" {
+	if tuple.Target == nil || tuple.Target.Kind == "" {
 		return errors.New("missing tuple.Target.Kind")
 	}
-	if tuple.Target.ID == "" && (tuple.Object.Kind == "" || tuple.Object.ID == "") {
+	// The Read API requires a user to look up tuples on all objects of a
+	// type, so, for instance, all tuples with a specific relation on objects
+	// of a specific type cannot be fetched.
+	if tuple.Target.ID == "" && (tuple.Object == nil || tuple.Object.Kind == "" || tuple.Object.ID == "") {
 		return errors.New("either tuple.Target.ID or tuple.Object must be specified")
 	}
 	if tuple.Target.Relation != "" {
@@ -420,15 +1959,23 @@ func validateTupleForFindMatchingTuples(tuple Tuple) error {
 //   - Alternatively, Tuple can be an empty struct passed in with all nil/empty
 //     values. In this case, all tuples from the system are returned.
 //
-// This method can be used to find all tuples where:
-//   - a specific user has a specific relation with objects of a specific type
+// When specified, Tuple.Relation is always applied as a filter by the server.
+// The supported queries are therefore:
+//   - Tuples of a specific user on objects of a specific type, optionally
+//     with a specific relation.
 //     eg: Find all documents where bob is a writer -
 //     ("user:bob", "writer", "document:")
-//   - a specific user has any relation with objects of a specific type
 //     eg: Find all documents related to bob - ("user:bob", "", "document:")
-//   - any user has any relation with a specific object
-//     eg: Find all documents related by a writer relation -
-//     ("", "", "document:planning")
+//   - Tuples on a specific object, optionally with a specific user and/or a
+//     specific relation.
+//     eg: Find all writers of a document - ("", "writer", "document:planning")
+//     eg: Find all users related to a document - ("", "", "document:planning")
+//   - All tuples in the system, with an empty tuple.
+//
+// Note that finding the tuples with a specific relation on any object of a
+// specific type, without specifying a user (e.g. ("", "writer", "document:")),
+// is not supported by the Read API and results in an error. In this case, all
+// tuples can be fetched and filtered by the caller instead.
 //
 // This method is also useful during authorization model migrations.
 func (c *Client) FindMatchingTuples(ctx context.Context, tuple Tuple, pageSize int32, continuationToken string) ([]TimestampedTuple, string, error) {
@@ -448,7 +1995,7 @@ func (c *Client) FindMatchingTuples(ctx context.Context, tuple Tuple, pageSize i
 	resp, _, err := c.api.Read(ctx, c.storeID).Body(*rr).Execute()
 	if err != nil {
 		zapctx.Error(ctx, fmt.Sprintf("cannot execute Read request: %v", err))
-		return nil, "", fmt.Errorf("cannot fetch matching tuples: %v", err)
+		return nil, "", fmt.Errorf("cannot fetch matching tuples: %w", err)
 	}
 	tuples := make([]TimestampedTuple, 0, len(resp.GetTuples()))
 	for _, oTuple := range resp.GetTuples() {
@@ -460,11 +2007,138 @@ func (c *Client) FindMatchingTuples(ctx context.Context, tuple Tuple, pageSize i
 		tuples = append(tuples, TimestampedTuple{
 			Tuple:     t,
 			Timestamp: oTuple.Timestamp,
+			// The timestamp is not a pointer in the OpenFGA API types, so a
+			// missing timestamp can only be detected as a zero value.
+			HasTimestamp: !oTuple.Timestamp.IsZero(),
 		})
 	}
 	return tuples, resp.GetContinuationToken(), nil
 }
 
+// FindMatchingTuplesAnyRelation fetches all stored relationship tuples between
+// the given object and target whose relation is any of the given relations.
+// For instance, it can be used to find all documents where bob is either an
+// editor or an owner - ("user:bob", ["editor", "owner"], "document:").
+//
+// The object and target have the same constraints as in FindMatchingTuples;
+// in particular, the object may be left empty if the target ID is specified.
+// Note that this method executes a separate Read request for each relation,
+// fetching all the pages of results with the specified pageSize, so that N
+// relations result in at least N requests. The results are merged and
+// duplicate tuples removed.
+func (c *Client) FindMatchingTuplesAnyRelation(ctx context.Context, object, target Entity, relations []Relation, pageSize int32) ([]TimestampedTuple, error) {
+	tuples := []TimestampedTuple{}
+	seen := make(map[string]bool)
+	for _, relation := range relations {
+		query := Tuple{
+			Relation: relation,
+			Target:   &target,
+		}
+		if object != (Entity{}) {
+			query.Object = &object
+		}
+		continuationToken := ""
+		for {
+			found, token, err := c.FindMatchingTuples(ctx, query, pageSize, continuationToken)
+			if err != nil {
+				return nil, err
+			}
+			for _, t := range found {
+				key := t.Tuple.String()
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				tuples = append(tuples, t)
+			}
+			if token == "" || token == continuationToken {
+				break
+			}
+			continuationToken = token
+		}
+	}
+	return tuples, nil
+}
+
+// FindTuplesReferencing fetches all stored relationship tuples in which the
+// given entity appears, either as the object (e.g. `user:bob` in
+// ("user:bob", "editor", "document:planning")) or as the target (e.g.
+// `document:planning` in the same tuple). This is useful, for instance, to
+// find the tuples left behind when an entity is deleted from the application,
+// so that they can be removed. The entity must specify only the Kind and ID.
+//
+// Note that this method issues two kinds of Read requests, whose results are
+// merged with duplicate tuples removed: one for the tuples targeting the
+// entity, and one for the tuples having the entity as object. As the Read API
+// requires the type of the target to be specified in the latter case, this
+// request is repeated for each type with relations defined in the
+// authorization model used by the client (or in the latest model, if the
+// client is not configured with one), which is fetched first. All pages of
+// results are fetched. Tuples referencing a userset of the entity (e.g.
+// `group:eng#member` for `group:eng`) are not returned.
+func (c *Client) FindTuplesReferencing(ctx context.Context, entity Entity) ([]TimestampedTuple, error) {
+	if entity.Kind == "" || entity.ID == "" || entity.Relation != "" {
+		return nil, errors.New("invalid entity for FindTuplesReferencing: only entity.Kind and entity.ID must be set")
+	}
+	model, err := c.currentAuthModel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	queries := []Tuple{{Target: &entity}}
+	for _, td := range model.TypeDefinitions {
+		if len(td.GetRelations()) == 0 {
+			continue
+		}
+		queries = append(queries, Tuple{
+			Object: &entity,
+			Target: &Entity{Kind: Kind(td.Type)},
+		})
+	}
+
+	tuples := []TimestampedTuple{}
+	seen := make(map[string]bool)
+	for _, query := range queries {
+		continuationToken := ""
+		for {
+			found, token, err := c.FindMatchingTuples(ctx, query, 0, continuationToken)
+			if err != nil {
+				return nil, err
+			}
+			for _, t := range found {
+				key := t.Tuple.String()
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				tuples = append(tuples, t)
+			}
+			if token == "" || token == continuationToken {
+				break
+			}
+			continuationToken = token
+		}
+	}
+	return tuples, nil
+}
+
+// currentAuthModel returns the authorization model used by the client, which
+// is the latest model in the store if the client is not configured with an
+// authorization model ID.
+func (c *Client) currentAuthModel(ctx context.Context) (openfga.AuthorizationModel, error) {
+	if c.authModelID != LatestAuthModel {
+		return c.GetAuthModel(ctx, c.authModelID)
+	}
+	resp, err := c.ListAuthModels(ctx, 1, "")
+	if err != nil {
+		return openfga.AuthorizationModel{}, err
+	}
+	models := resp.GetAuthorizationModels()
+	if len(models) == 0 {
+		return openfga.AuthorizationModel{}, errors.New("no authorization models found in the store")
+	}
+	return models[0], nil
+}
+
 // FindUsersByRelation fetches the list of users that have a specific
 // relation with a specific target object. This method not only searches
 // through the relationship tuples present in the system, but also takes into
@@ -475,7 +2149,27 @@ func (c *Client) FindMatchingTuples(ctx context.Context, tuple Tuple, pageSize i
 // the current tuple to be expanded and the immediate expansion results to be
 // returned. `maxDepth` can be any positive number.
 //
+// Users excluded by the authorization model (e.g. `viewer: [user] but not
+// blocked`) are removed from the results. Note that exclusions cannot be
+// applied to public access entities (`user:*`), which are returned as is. If
+// either the users from which others are excluded or the excluded users
+// include usersets that cannot be expanded within `maxDepth` levels, an error
+// is returned rather than results possibly including excluded users.
+//
 // This method requires that Tuple.Target and Tuple.Relation be specified.
+// Tuple.Target must not include a relation, as the underlying Expand API only
+// accepts objects: the users of a userset such as `group:eng#member` are
+// found by setting Tuple.Target to `group:eng` and Tuple.Relation to
+// `member`.
+//
+// If the given context is cancelled while users are being expanded, the
+// expansion stops and the returned error wraps the context error. Likewise,
+// if OpenFGAParams.MaxExpandUsers is set and more users and usersets are
+// found, the expansion stops and the returned error wraps
+// ErrExpandLimitExceeded.
+//
+// The underlying Expand API is not paginated and always returns the whole
+// userset tree, so the results are never truncated.
 //
 // Note that this method call is expensive and has high latency, and should be
 // used with caution. The official docs state that the underlying API method
@@ -484,7 +2178,11 @@ func (c *Client) FindUsersByRelation(ctx context.Context, tuple Tuple, maxDepth
 	if maxDepth < 1 {
 		return nil, errors.New(`maxDepth must be greater than or equal to 1`)
 	}
-	userStrings, err := c.findUsersByRelation(ctx, tuple, maxDepth)
+	var counter *expandCounter
+	if c.maxExpandUsers > 0 {
+		counter = &expandCounter{limit: c.maxExpandUsers}
+	}
+	userStrings, err := c.findUsersByRelation(ctx, tuple, maxDepth, counter)
 	if err != nil {
 		return nil, err
 	}
@@ -499,10 +2197,49 @@ func (c *Client) FindUsersByRelation(ctx context.Context, tuple Tuple, maxDepth
 	return users, nil
 }
 
+// ExpandTree returns the tree of users that have a specific relation with a
+// specific target object, as returned by the underlying Expand API from
+// openFGA. Unlike FindUsersByRelation, the tree is returned as is, without
+// expanding the usersets in its leaves, so that its structure (unions,
+// intersections and exclusions defined by the authorization model) can be
+// inspected, for instance to visualize how access is derived.
+//
+// This method requires that Tuple.Target and Tuple.Relation be specified, with
+// the same constraints described in FindUsersByRelation.
+func (c *Client) ExpandTree(ctx context.Context, tuple Tuple) (*openfga.UsersetTree, error) {
+	if err := validateTupleForFindUsersByRelation(tuple); err != nil {
+		return nil, fmt.Errorf("invalid tuple for ExpandTree: %v", err)
+	}
+	tree, err := c.expandTree(ctx, tuple)
+	if err != nil {
+		return nil, err
+	}
+	return &tree, nil
+}
+
+// expandTree executes an Expand request for the given tuple and returns the
+// resulting tree, which is guaranteed to have a root.
+func (c *Client) expandTree(ctx context.Context, tuple Tuple) (openfga.UsersetTree, error) {
+	er := openfga.NewExpandRequest(*tuple.ToOpenFGAExpandRequestTupleKey())
+	if c.authModelID != LatestAuthModel {
+		er.SetAuthorizationModelId(c.authModelID)
+	}
+	resp, _, err := c.api.Expand(ctx, c.storeID).Body(*er).Execute()
+	if err != nil {
+		zapctx.Error(ctx, fmt.Sprintf("cannot execute Expand request: %v", err))
+		return openfga.UsersetTree{}, fmt.Errorf("cannot execute Expand request: %w", err)
+	}
+	tree := resp.GetTree()
+	if !tree.HasRoot() {
+		return openfga.UsersetTree{}, errors.New("tree from Expand response has no root")
+	}
+	return tree, nil
+}
+
 // validateTupleForFindUsersByRelation validates that the input tuples to the
 // FindMatchingTuples method complies with the API requirements.
 func validateTupleForFindUsersByRelation(tuple Tuple) error {
-	if tuple.Target.Kind == "" || tuple.Target.ID == "" {
+	if tuple.Target == nil || tuple.Target.Kind == "" || tuple.Target.ID == "" {
 		return errors.New("missing tuple.Target")
 	}
 	if tuple.Target.Relation != "" {
@@ -517,11 +2254,17 @@ func validateTupleForFindUsersByRelation(tuple Tuple) error {
 // findUsersByRelation is the internal implementation for
 // FindUsersByRelation. It returns a set of userStrings representing the
 // list of users that have access to the specified object via the specified
-// relation.
-func (c *Client) findUsersByRelation(ctx context.Context, tuple Tuple, maxDepth int) (map[string]bool, error) {
+// relation. The users and usersets found are recorded in the given counter,
+// if not nil.
+func (c *Client) findUsersByRelation(ctx context.Context, tuple Tuple, maxDepth int, counter *expandCounter) (map[string]bool, error) {
 	if err := validateTupleForFindUsersByRelation(tuple); err != nil {
 		return nil, fmt.Errorf("invalid tuple for FindUsersByRelation: %v", err)
 	}
+	// Stop expanding as soon as the context is done, rather than waiting for
+	// the next API call to fail.
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	// If we have reached the maxDepth and shouldn't expand the results further,
 	// return the current userSet.
 	if maxDepth == 0 {
@@ -532,30 +2275,26 @@ func (c *Client) findUsersByRelation(ctx context.Context, tuple Tuple, maxDepth
 		}, nil
 	}
 
-	er := openfga.NewExpandRequest(*tuple.ToOpenFGAExpandRequestTupleKey())
-	er.SetAuthorizationModelId(c.authModelID)
-	resp, _, err := c.api.Expand(ctx, c.storeID).Body(*er).Execute()
+	tree, err := c.expandTree(ctx, tuple)
 	if err != nil {
-		zapctx.Error(ctx, fmt.Sprintf("cannot execute Expand request: %v", err))
-		return nil, fmt.Errorf("cannot execute Expand request: %v", err)
-	}
-
-	tree := resp.GetTree()
-	if !tree.HasRoot() {
-		return nil, errors.New("tree from Expand response has no root")
+		return nil, err
 	}
 	root := tree.GetRoot()
-	leaves, err := c.traverseTree(ctx, &root, maxDepth-1)
+	leaves, err := c.traverseTree(ctx, &root, maxDepth-1, counter)
 	if err != nil {
-		return nil, fmt.Errorf("cannot expand the intermediate results: %v", err)
+		return nil, fmt.Errorf("cannot expand the intermediate results: %w", err)
 	}
 	return leaves, nil
 }
 
 // traverseTree will recursively expand the tree returned by an openfga Expand
 // call to find all users that have the specified relation to the specified
-// target entity.
-func (c *Client) traverseTree(ctx context.Context, node *openfga.Node, maxDepth int) (map[string]bool, error) {
+// target entity. The traversal stops as soon as the given context is done,
+// returning the context error.
+func (c *Client) traverseTree(ctx context.Context, node *openfga.Node, maxDepth int, counter *expandCounter) (map[string]bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	logError := func(message, nodeType string, n interface{}) {
 		data, _ := json.Marshal(n)
 		zapctx.Error(ctx, message, zap.String(nodeType, string(data)))
@@ -568,7 +2307,7 @@ func (c *Client) traverseTree(ctx context.Context, node *openfga.Node, maxDepth
 		users := make(map[string]bool)
 		for _, childNode := range union.GetNodes() {
 			childNode := childNode
-			childNodeUsers, err := c.traverseTree(ctx, &childNode, maxDepth)
+			childNodeUsers, err := c.traverseTree(ctx, &childNode, maxDepth, counter)
 			if err != nil {
 				return nil, err
 			}
@@ -579,6 +2318,40 @@ func (c *Client) traverseTree(ctx context.Context, node *openfga.Node, maxDepth
 		return users, nil
 	}
 
+	// If this is a difference node (e.g. `viewer: [user] but not blocked`),
+	// we traverse both the base and the subtracted nodes and remove the
+	// subtracted users from the base ones. A public access entity (`user:*`)
+	// in the subtracted users removes all the base users of the same kind,
+	// while users excluded from a public access entity in the base users
+	// cannot be represented and are therefore ignored. Both the base and the
+	// subtracted users must be fully expanded, as the users of a userset left
+	// unexpanded by maxDepth would otherwise be returned without the
+	// exclusion being applied to them.
+	if node.HasDifference() {
+		difference := node.GetDifference()
+		users, err := c.traverseTree(ctx, &difference.Base, maxDepth, counter)
+		if err != nil {
+			return nil, err
+		}
+		excluded, err := c.traverseTree(ctx, &difference.Subtract, maxDepth, counter)
+		if err != nil {
+			return nil, err
+		}
+		if unexpanded := unexpandedUsersets(excluded); len(unexpanded) > 0 {
+			return nil, fmt.Errorf("cannot exclude the users of %s: userset cannot be expanded within maxDepth", strings.Join(unexpanded, ", "))
+		}
+		if unexpanded := unexpandedUsersets(users); len(unexpanded) > 0 {
+			return nil, fmt.Errorf("cannot exclude users from the users of %s: userset cannot be expanded within maxDepth", strings.Join(unexpanded, ", "))
+		}
+		for userString := range users {
+			kind, _, _ := strings.Cut(userString, ":")
+			if excluded[userString] || excluded[kind+":*"] {
+				delete(users, userString)
+			}
+		}
+		return users, nil
+	}
+
 	if !node.HasLeaf() {
 		logError("unknown node type", "node", node)
 		return nil, errors.New("unknown node type")
@@ -603,7 +2376,7 @@ func (c *Client) traverseTree(ctx context.Context, node *openfga.Node, maxDepth
 	// If the leaf node contains computedSets or tupleToUserSets, we need
 	// to expand them further to obtain individual users.
 	if leaf.HasUsers() {
-		users, err := c.expand(ctx, maxDepth, leaf.Users.Users...)
+		users, err := c.expand(ctx, maxDepth, counter, leaf.Users.Users...)
 		if err != nil {
 			return nil, err
 		}
@@ -611,14 +2384,14 @@ func (c *Client) traverseTree(ctx context.Context, node *openfga.Node, maxDepth
 	}
 
 	if leaf.HasComputed() {
-		return c.expandComputed(ctx, maxDepth, leaf, leaf.GetComputed())
+		return c.expandComputed(ctx, maxDepth, counter, leaf, leaf.GetComputed())
 	}
 
 	if leaf.HasTupleToUserset() {
 		tupleToUserSet := leaf.GetTupleToUserset()
 		computed := tupleToUserSet.GetComputed()
 		if len(computed) > 0 {
-			return c.expandComputed(ctx, maxDepth, leaf, computed...)
+			return c.expandComputed(ctx, maxDepth, counter, leaf, computed...)
 		}
 	}
 
@@ -626,10 +2399,23 @@ func (c *Client) traverseTree(ctx context.Context, node *openfga.Node, maxDepth
 	return nil, errors.New("unknown leaf type")
 }
 
+// unexpandedUsersets returns the sorted usersets (e.g. `team:eng#member`)
+// included in the given set of userStrings.
+func unexpandedUsersets(userStrings map[string]bool) []string {
+	var usersets []string
+	for userString := range userStrings {
+		if strings.Contains(userString, "#") {
+			usersets = append(usersets, userString)
+		}
+	}
+	slices.Sort(usersets)
+	return usersets
+}
+
 // expandComputed is a helper method to expand a computedSet into its
 // constituent users. The leaf parameter of this function is used for
 // logging purposes only.
-func (c *Client) expandComputed(ctx context.Context, maxDepth int, leaf openfga.Leaf, computedList ...openfga.Computed) (map[string]bool, error) {
+func (c *Client) expandComputed(ctx context.Context, maxDepth int, counter *expandCounter, leaf openfga.Leaf, computedList ...openfga.Computed) (map[string]bool, error) {
 	logError := func(message, nodeType string, n interface{}) {
 		data, _ := json.Marshal(n)
 		zapctx.Error(ctx, message, zap.String(nodeType, string(data)))
@@ -638,7 +2424,7 @@ func (c *Client) expandComputed(ctx context.Context, maxDepth int, leaf openfga.
 	for _, computed := range computedList {
 		userSet := computed.GetUserset()
 		if userSet != "" {
-			found, err := c.expand(ctx, maxDepth, userSet)
+			found, err := c.expand(ctx, maxDepth, counter, userSet)
 			if err != nil {
 				return nil, err
 			}
@@ -656,7 +2442,10 @@ func (c *Client) expandComputed(ctx context.Context, maxDepth int, leaf openfga.
 // expand checks all userStrings in the input list and expands any userSets
 // that are present into the constituent individual users. Example:
 // "team:planning#members" would be expanded into "user:abc", "user:xyz", etc.
-func (c *Client) expand(ctx context.Context, maxDepth int, userStrings ...string) (map[string]bool, error) {
+func (c *Client) expand(ctx context.Context, maxDepth int, counter *expandCounter, userStrings ...string) (map[string]bool, error) {
+	if err := counter.add(len(userStrings)); err != nil {
+		return nil, err
+	}
 	users := make(map[string]bool, len(userStrings))
 	for _, u := range userStrings {
 		tokens := strings.Split(u, "#")
@@ -675,9 +2464,9 @@ func (c *Client) expand(ctx context.Context, maxDepth int, userStrings ...string
 			if err != nil {
 				return nil, fmt.Errorf("failed to parse tuple %s, %v", u, err)
 			}
-			found, err := c.findUsersByRelation(ctx, tuple, maxDepth)
+			found, err := c.findUsersByRelation(ctx, tuple, maxDepth, counter)
 			if err != nil {
-				return nil, fmt.Errorf("failed to expand %s, %v", u, err)
+				return nil, fmt.Errorf("failed to expand %s, %w", u, err)
 			}
 			for userString := range found {
 				users[userString] = true
@@ -690,6 +2479,27 @@ func (c *Client) expand(ctx context.Context, maxDepth int, userStrings ...string
 	return users, nil
 }
 
+// expandCounter counts the users and usersets found while expanding userset
+// trees, enforcing OpenFGAParams.MaxExpandUsers.
+type expandCounter struct {
+	limit int
+	found int
+}
+
+// add records that n users or usersets were found, returning an error if the
+// limit of the counter is exceeded. No limit is enforced if the counter is
+// nil.
+func (counter *expandCounter) add(n int) error {
+	if counter == nil {
+		return nil
+	}
+	counter.found += n
+	if counter.found > counter.limit {
+		return fmt.Errorf("%w: more than %d users and usersets found", ErrExpandLimitExceeded, counter.limit)
+	}
+	return nil
+}
+
 // validateTupleForFindAccessibleObjectsByRelation validates that the input
 // tuples to the FindAccessibleObjectsByRelation method complies with the API
 // requirements.
@@ -722,36 +2532,362 @@ func validateTupleForFindAccessibleObjectsByRelation(tuple Tuple) error {
 // Note that there are some important caveats to using this method (suboptimal
 // performance depending on the authorization model, experimental, subject to
 // context deadlines, See: https://openfga.dev/docs/interacting/relationship-queries#caveats-and-when-not-to-use-it-3
+//
+// The ListObjects API only returns the objects, without any information on
+// how access to each of them was resolved. To find out why an unexpected
+// object is returned, use CheckRelationWithTracing on the corresponding tuple.
 func (c *Client) FindAccessibleObjectsByRelation(ctx context.Context, tuple Tuple, contextualTuples ...Tuple) ([]Entity, error) {
+	return c.FindAccessibleObjectsByRelationWithOptions(ctx, tuple, FindAccessibleObjectsOptions{
+		ContextualTuples: contextualTuples,
+	})
+}
+
+// FindAccessibleObjectsByRelationWithLimit behaves like
+// FindAccessibleObjectsByRelation, but returns at most `limit` objects.
+// `limit` must be a positive number.
+//
+// Note that the OpenFGA client used by this library does not provide access to
+// the streaming variant of the ListObjects API, so the limit is applied to the
+// results returned by the non-streaming endpoint. The server's own limit on
+// the number of results returned by ListObjects (and its deadline) still
+// applies, so fewer than `limit` objects may be returned even if more objects
+// are accessible.
+func (c *Client) FindAccessibleObjectsByRelationWithLimit(ctx context.Context, tuple Tuple, limit int, contextualTuples ...Tuple) ([]Entity, error) {
+	if limit < 1 {
+		return nil, errors.New(`limit must be greater than or equal to 1`)
+	}
+	return c.FindAccessibleObjectsByRelationWithOptions(ctx, tuple, FindAccessibleObjectsOptions{
+		ContextualTuples: contextualTuples,
+		Limit:            limit,
+	})
+}
+
+// FindAccessibleObjectsOptions holds optional parameters for the
+// FindAccessibleObjectsByRelationWithOptions method.
+type FindAccessibleObjectsOptions struct {
+	// ContextualTuples specifies temporary, non-persistent relationship
+	// tuples that are taken into account for this request as if they were
+	// present in the store.
+	ContextualTuples []Tuple
+	// Context specifies additional request context used to evaluate any
+	// conditions encountered while resolving the request. Objects are only
+	// returned if the conditions they depend on are satisfied, as done by
+	// CheckRelationWithOptions with the same context.
+	Context map[string]any
+	// Limit specifies the maximum number of objects returned. If set to 0,
+	// all objects returned by the server are returned. See
+	// FindAccessibleObjectsByRelationWithLimit for caveats.
+	Limit int
+	// Sorted causes duplicate objects to be removed from the results, and
+	// the remaining objects to be sorted by ID, providing a deterministic
+	// output. When a Limit is also specified, it is applied after sorting.
+	Sorted bool
+}
+
+// FindAccessibleObjectsByRelationWithOptions behaves like
+// FindAccessibleObjectsByRelation, additionally allowing the results to be
+// limited, de-duplicated and sorted as specified by opts.
+func (c *Client) FindAccessibleObjectsByRelationWithOptions(ctx context.Context, tuple Tuple, opts FindAccessibleObjectsOptions) ([]Entity, error) {
+	if opts.Limit < 0 {
+		return nil, errors.New(`limit must not be negative`)
+	}
+	found, err := c.listObjects(ctx, tuple, opts.Context, opts.ContextualTuples)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Sorted {
+		// All objects have the same type, so sorting the string
+		// representations sorts the objects by ID.
+		found = slices.Clone(found)
+		slices.Sort(found)
+		found = slices.Compact(found)
+	}
+	if opts.Limit > 0 && len(found) > opts.Limit {
+		found = found[:opts.Limit]
+	}
+	objects := make([]Entity, 0, len(found))
+	for _, o := range found {
+		e, err := ParseEntity(o)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse entity %s from ListObjects response: %v", o, err)
+		}
+		objects = append(objects, e)
+	}
+
+	return objects, nil
+}
+
+// FindUserGrants returns, for each relation defined on the targetKind type
+// in the authorization model used by the client (or in the latest model, if
+// the client is not configured with one), the objects of that type the given
+// user (or any other entity) has access to via the relation. Relations
+// granting access to no objects are not included in the returned map. This
+// is useful, for instance, to show all the documents a user can access in a
+// dashboard. The user must specify only the Kind and ID.
+//
+// Note that this method is expensive: after fetching the authorization model,
+// it issues a ListObjects request for each relation defined on targetKind,
+// and is subject to the same caveats as FindAccessibleObjectsByRelation.
+func (c *Client) FindUserGrants(ctx context.Context, user Entity, targetKind Kind) (map[Relation][]Entity, error) {
+	if user.Kind == "" || user.ID == "" || user.Relation != "" {
+		return nil, errors.New("invalid user for FindUserGrants: only user.Kind and user.ID must be set")
+	}
+	model, err := c.currentAuthModel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	i := slices.IndexFunc(model.TypeDefinitions, func(td openfga.TypeDefinition) bool {
+		return td.Type == targetKind.String()
+	})
+	if i == -1 {
+		return nil, fmt.Errorf("type %q not found in the authorization model", targetKind)
+	}
+	relations := make([]string, 0, len(model.TypeDefinitions[i].GetRelations()))
+	for r := range model.TypeDefinitions[i].GetRelations() {
+		relations = append(relations, r)
+	}
+	slices.Sort(relations)
+
+	grants := make(map[Relation][]Entity)
+	for _, r := range relations {
+		objects, err := c.FindAccessibleObjectsByRelation(ctx, Tuple{
+			Object:   &user,
+			Relation: Relation(r),
+			Target:   &Entity{Kind: targetKind},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(objects) > 0 {
+			grants[Relation(r)] = objects
+		}
+	}
+	return grants, nil
+}
+
+// FindAccessibleObjectIDsByRelation behaves like
+// FindAccessibleObjectsByRelation, but returns the objects exactly as returned
+// by the ListObjects API (e.g. `document:abc`), without parsing them into
+// entities. This avoids the parsing cost when callers only need to pass the
+// objects on, and does not fail when the response includes objects that
+// cannot be parsed.
+func (c *Client) FindAccessibleObjectIDsByRelation(ctx context.Context, tuple Tuple, contextualTuples ...Tuple) ([]string, error) {
+	return c.listObjects(ctx, tuple, nil, contextualTuples)
+}
+
+// listObjects returns the objects of type tuple.Target.Kind that
+// tuple.Object has the tuple.Relation relation with, as returned by the
+// ListObjects API. The conditionContext, if not nil, is used to evaluate any
+// conditions encountered while resolving the request.
+func (c *Client) listObjects(ctx context.Context, tuple Tuple, conditionContext map[string]any, contextualTuples []Tuple) ([]string, error) {
 	if err := validateTupleForFindAccessibleObjectsByRelation(tuple); err != nil {
 		return nil, fmt.Errorf("invalid tuple for FindAccessibleObjectsByRelation: %v", err)
 	}
 
 	lor := openfga.NewListObjectsRequestWithDefaults()
-	lor.SetAuthorizationModelId(c.authModelID)
+	if c.authModelID != LatestAuthModel {
+		lor.SetAuthorizationModelId(c.authModelID)
+	}
 	lor.SetUser(tuple.Object.String())
 	lor.SetRelation(tuple.Relation.String())
 	lor.SetType(tuple.Target.Kind.String())
 
 	if len(contextualTuples) > 0 {
-		keys := tuplesToOpenFGATupleKeys(contextualTuples)
+		keys := contextualTuplesToOpenFGATupleKeys(contextualTuples)
 		lor.SetContextualTuples(*openfga.NewContextualTupleKeys(keys))
 	}
+	if conditionContext != nil {
+		lor.SetContext(conditionContext)
+	}
 
 	resp, _, err := c.api.ListObjects(ctx, c.storeID).Body(*lor).Execute()
 	if err != nil {
 		zapctx.Error(ctx, fmt.Sprintf("cannot execute ListObjects request: %v", err))
-		return nil, fmt.Errorf("cannot list objects: %v", err)
+		return nil, fmt.Errorf("cannot list objects: %w", err)
 	}
+	return resp.GetObjects(), nil
+}
 
-	objects := make([]Entity, 0, len(resp.GetObjects()))
-	for _, o := range resp.GetObjects() {
-		e, err := ParseEntity(o)
+// ListUsersOptions holds optional parameters for the ListUsers method.
+type ListUsersOptions struct {
+	// ContextualTuples specifies temporary, non-persistent relationship
+	// tuples that are taken into account for this request as if they were
+	// present in the store.
+	ContextualTuples []Tuple
+	// Context specifies additional request context used to evaluate any
+	// conditions encountered while resolving the request.
+	Context map[string]any
+	// DirectOnly restricts the results to users that were explicitly granted
+	// the relation by a relationship tuple stored in the system, excluding
+	// users that have the relation only by virtue of the authorization model
+	// (e.g. writers being implied viewers, or members of a granted userset).
+	// Stored tuples are listed using the Read API, so conditions attached to
+	// the tuples are not evaluated. ContextualTuples and Context cannot be
+	// specified along with DirectOnly.
+	DirectOnly bool
+}
+
+// validateTupleForListUsers validates that the input tuples to the ListUsers
+// method complies with the API requirements.
+func validateTupleForListUsers(tuple Tuple) error {
+	if tuple.Object == nil || tuple.Object.Kind == "" || tuple.Object.ID != "" {
+		return errors.New("only tuple.Object.Kind and optionally tuple.Object.Relation must be set")
+	}
+	if tuple.Relation == "" {
+		return errors.New("missing tuple.Relation")
+	}
+	if tuple.Target == nil || tuple.Target.Kind == "" || tuple.Target.ID == "" {
+		return errors.New("missing tuple.Target")
+	}
+	if tuple.Target.Relation != "" {
+		return errors.New("tuple.Target.Relation must not be set")
+	}
+	return nil
+}
+
+// listUsersMinServerVersion holds the minimum version of the OpenFGA server
+// implementing the ListUsers API.
+const listUsersMinServerVersion = "1.5.6"
+
+// conditionsMinServerVersion holds the minimum version of the OpenFGA server
+// supporting relationship conditions.
+const conditionsMinServerVersion = "1.4.0"
+
+// isUndefinedEndpointError reports whether the given error was returned
+// because the OpenFGA server does not implement the requested API, which
+// happens when the server is older than the version introducing it.
+func isUndefinedEndpointError(err error) bool {
+	var notFoundErr openfga.FgaApiNotFoundError
+	if !errors.As(err, &notFoundErr) {
+		return false
+	}
+	code := notFoundErr.ResponseCode()
+	return code == openfga.NOTFOUNDERRORCODE_UNDEFINED_ENDPOINT || code == openfga.NOTFOUNDERRORCODE_UNIMPLEMENTED
+}
+
+// ListUsers returns the list of users of a specific type that have a specific
+// relation with a specific target object. Both the relationship tuples present
+// in the system and the relationships implied by the authorization model are
+// taken into account. This method uses the underlying ListUsers API from
+// openFGA.
+//
+// This method has some constraints on the tuples passed in (the constraints
+// are from the underlying openfga.ListUsers API):
+//   - The tuple.Object field must specify only the Kind of the users to be
+//     returned and, optionally, a Relation to return usersets instead (e.g.
+//     `group#member`).
+//   - The tuple.Relation field must be set.
+//   - The tuple.Target field must specify the Kind and ID.
+//
+// The opts parameter can be used to specify contextual tuples and a context
+// object used to evaluate conditions, or to restrict the results to users
+// explicitly granted the relation (see ListUsersOptions.DirectOnly).
+//
+// Returned entities may represent specific users (`user:alice`), usersets
+// (`group:eng#member`) or public access (`user:*`). Exclusions defined by the
+// authorization model (e.g. `viewer: [user] but not blocked`) are applied by
+// the server, so excluded users are not returned.
+//
+// Note that the ListUsers API is not paginated, and does not return a
+// continuation token: the server returns at most the number of users
+// configured with its `--listUsers-max-results` flag (1000 by default), and
+// the results may also be incomplete if the `--listUsers-deadline` is
+// reached. Use FindUsersByRelation or FindMatchingTuples to retrieve all the
+// users of targets that may have more users than that.
+//
+// The ListUsers API requires OpenFGA 1.5.6 or later: an error stating so, and
+// wrapping the error returned by the server, is returned if the server does
+// not implement it.
+func (c *Client) ListUsers(ctx context.Context, tuple Tuple, opts ListUsersOptions) ([]Entity, error) {
+	if err := validateTupleForListUsers(tuple); err != nil {
+		return nil, fmt.Errorf("invalid tuple for ListUsers: %v", err)
+	}
+	if opts.DirectOnly {
+		if len(opts.ContextualTuples) > 0 || opts.Context != nil {
+			return nil, errors.New("contextual tuples and context cannot be used to list direct users")
+		}
+		return c.listDirectUsers(ctx, tuple)
+	}
+
+	filter := openfga.NewUserTypeFilter(tuple.Object.Kind.String())
+	if tuple.Object.Relation != "" {
+		filter.SetRelation(tuple.Object.Relation.String())
+	}
+	lur := openfga.NewListUsersRequest(
+		*openfga.NewFgaObject(tuple.Target.Kind.String(), tuple.Target.ID),
+		tuple.Relation.String(),
+		[]openfga.UserTypeFilter{*filter},
+	)
+	if c.authModelID != LatestAuthModel {
+		lur.SetAuthorizationModelId(c.authModelID)
+	}
+	if len(opts.ContextualTuples) > 0 {
+		lur.SetContextualTuples(contextualTuplesToOpenFGATupleKeys(opts.ContextualTuples))
+	}
+	if opts.Context != nil {
+		lur.SetContext(opts.Context)
+	}
+
+	resp, _, err := c.api.ListUsers(ctx, c.storeID).Body(*lur).Execute()
+	if err != nil {
+		zapctx.Error(ctx, fmt.Sprintf("cannot execute ListUsers request: %v", err))
+		if isUndefinedEndpointError(err) {
+			return nil, fmt.Errorf("cannot list users: ListUsers requires OpenFGA >= %s: %w", listUsersMinServerVersion, err)
+		}
+		return nil, fmt.Errorf("cannot list users: %w", err)
+	}
+
+	users := make([]Entity, 0, len(resp.GetUsers()))
+	for _, u := range resp.GetUsers() {
+		user, err := fromOpenFGAUser(u)
 		if err != nil {
-			return nil, fmt.Errorf("cannot parse entity %s from ListObjects response: %v", o, err)
+			return nil, fmt.Errorf("cannot parse user from ListUsers response: %v", err)
 		}
-		objects = append(objects, e)
+		users = append(users, user)
 	}
+	return users, nil
+}
 
-	return objects, nil
+// listDirectUsers returns the users that match the user filter specified by
+// tuple.Object and have the tuple.Relation relation with tuple.Target via
+// relationship tuples stored in the system.
+func (c *Client) listDirectUsers(ctx context.Context, tuple Tuple) ([]Entity, error) {
+	query := Tuple{
+		Relation: tuple.Relation,
+		Target:   tuple.Target,
+	}
+	users := []Entity{}
+	continuationToken := ""
+	for {
+		tuples, token, err := c.FindMatchingTuples(ctx, query, 0, continuationToken)
+		if err != nil {
+			return nil, fmt.Errorf("cannot list direct users: %w", err)
+		}
+		for _, t := range tuples {
+			o := t.Tuple.Object
+			if o.Kind == tuple.Object.Kind && o.Relation == tuple.Object.Relation {
+				users = append(users, *o)
+			}
+		}
+		if token == "" || token == continuationToken {
+			return users, nil
+		}
+		continuationToken = token
+	}
+}
+
+// fromOpenFGAUser converts an openfga.User into an Entity.
+func fromOpenFGAUser(u openfga.User) (Entity, error) {
+	switch {
+	case u.HasObject():
+		o := u.GetObject()
+		return Entity{Kind: Kind(o.GetType()), ID: o.GetId()}, nil
+	case u.HasUserset():
+		us := u.GetUserset()
+		return Entity{Kind: Kind(us.GetType()), ID: us.GetId(), Relation: Relation(us.GetRelation())}, nil
+	case u.HasWildcard():
+		w := u.GetWildcard()
+		return Entity{Kind: Kind(w.GetType()), ID: "*"}, nil
+	}
+	return Entity{}, errors.New("unknown user type")
 }
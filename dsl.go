@@ -0,0 +1,621 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the LGPL license, see LICENSE file for details.
+
+package ofga
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	openfga "github.com/openfga/go-sdk"
+)
+
+// AuthModelToDSL renders the given authorization model using the OpenFGA
+// modeling language (DSL). Type definitions are rendered in the order in which
+// they appear in the model, while relations and conditions are sorted by name,
+// as their order is not preserved by the OpenFGA API.
+//
+// Only schema version 1.1 models are supported.
+func AuthModelToDSL(model openfga.AuthorizationModel) (string, error) {
+	if model.SchemaVersion != "1.1" {
+		return "", fmt.Errorf("unsupported schema version %q", model.SchemaVersion)
+	}
+	var b strings.Builder
+	b.WriteString("model\n  schema 1.1\n")
+	for _, td := range model.TypeDefinitions {
+		b.WriteString("\ntype ")
+		b.WriteString(td.Type)
+		b.WriteString("\n")
+		relations := td.GetRelations()
+		if len(relations) == 0 {
+			continue
+		}
+		var metadata map[string]openfga.RelationMetadata
+		if td.Metadata != nil {
+			metadata = td.Metadata.GetRelations()
+		}
+		names := make([]string, 0, len(relations))
+		for name := range relations {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		b.WriteString("  relations\n")
+		for _, name := range names {
+			relMetadata := metadata[name]
+			def, err := usersetToDSL(relations[name], relMetadata.GetDirectlyRelatedUserTypes(), false)
+			if err != nil {
+				return "", fmt.Errorf("cannot render relation %s#%s: %v", td.Type, name, err)
+			}
+			fmt.Fprintf(&b, "    define %s: %s\n", name, def)
+		}
+	}
+	conditions := model.GetConditions()
+	names := make([]string, 0, len(conditions))
+	for name := range conditions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		cond := conditions[name]
+		params := cond.GetParameters()
+		paramNames := make([]string, 0, len(params))
+		for paramName := range params {
+			paramNames = append(paramNames, paramName)
+		}
+		sort.Strings(paramNames)
+		args := make([]string, len(paramNames))
+		for i, paramName := range paramNames {
+			typ, err := conditionParamTypeToDSL(params[paramName])
+			if err != nil {
+				return "", fmt.Errorf("cannot render condition %s: %v", name, err)
+			}
+			args[i] = paramName + ": " + typ
+		}
+		fmt.Fprintf(&b, "\ncondition %s(%s) {\n  %s\n}\n", name, strings.Join(args, ", "), cond.Expression)
+	}
+	return b.String(), nil
+}
+
+// usersetToDSL returns the DSL representation of the given userset. The
+// directly related user types are used to render direct relationships. If
+// nested is true, operations are wrapped in parentheses.
+func usersetToDSL(u openfga.Userset, directTypes []openfga.RelationReference, nested bool) (string, error) {
+	var s string
+	switch {
+	case u.This != nil:
+		types := make([]string, len(directTypes))
+		for i, ref := range directTypes {
+			types[i] = relationReferenceToDSL(ref)
+		}
+		return "[" + strings.Join(types, ", ") + "]", nil
+	case u.ComputedUserset != nil:
+		return u.ComputedUserset.GetRelation(), nil
+	case u.TupleToUserset != nil:
+		return u.TupleToUserset.ComputedUserset.GetRelation() + " from " + u.TupleToUserset.Tupleset.GetRelation(), nil
+	case u.Union != nil:
+		children, err := usersetsToDSL(u.Union.Child, directTypes)
+		if err != nil {
+			return "", err
+		}
+		s = strings.Join(children, " or ")
+	case u.Intersection != nil:
+		children, err := usersetsToDSL(u.Intersection.Child, directTypes)
+		if err != nil {
+			return "", err
+		}
+		s = strings.Join(children, " and ")
+	case u.Difference != nil:
+		children, err := usersetsToDSL([]openfga.Userset{u.Difference.Base, u.Difference.Subtract}, directTypes)
+		if err != nil {
+			return "", err
+		}
+		s = children[0] + " but not " + children[1]
+	default:
+		return "", errors.New("empty userset")
+	}
+	if nested {
+		s = "(" + s + ")"
+	}
+	return s, nil
+}
+
+// usersetsToDSL returns the DSL representation of each of the given usersets
+// as operands of an operation.
+func usersetsToDSL(usersets []openfga.Userset, directTypes []openfga.RelationReference) ([]string, error) {
+	children := make([]string, len(usersets))
+	for i, child := range usersets {
+		s, err := usersetToDSL(child, directTypes, true)
+		if err != nil {
+			return nil, err
+		}
+		children[i] = s
+	}
+	return children, nil
+}
+
+// relationReferenceToDSL returns the DSL representation of a directly related
+// user type, for instance "user", "user:*", "group#member" or
+// "user with condition".
+func relationReferenceToDSL(ref openfga.RelationReference) string {
+	s := ref.Type
+	switch {
+	case ref.Wildcard != nil:
+		s += ":*"
+	case ref.GetRelation() != "":
+		s += "#" + ref.GetRelation()
+	}
+	if ref.GetCondition() != "" {
+		s += " with " + ref.GetCondition()
+	}
+	return s
+}
+
+// conditionParamTypeToDSL returns the DSL representation of the type of a
+// condition parameter, for instance "string" or "map<int>".
+func conditionParamTypeToDSL(ref openfga.ConditionParamTypeRef) (string, error) {
+	name, ok := strings.CutPrefix(string(ref.TypeName), "TYPE_NAME_")
+	if !ok || ref.TypeName == openfga.TYPENAME_UNSPECIFIED {
+		return "", fmt.Errorf("invalid parameter type %q", ref.TypeName)
+	}
+	s := strings.ToLower(name)
+	if generics := ref.GetGenericTypes(); len(generics) > 0 {
+		args := make([]string, len(generics))
+		for i, g := range generics {
+			arg, err := conditionParamTypeToDSL(g)
+			if err != nil {
+				return "", err
+			}
+			args[i] = arg
+		}
+		s += "<" + strings.Join(args, ", ") + ">"
+	}
+	return s, nil
+}
+
+// AuthModelFromDSL converts the input representation of an authorization model
+// written in the OpenFGA modeling language (DSL) into an
+// [openfga.AuthorizationModel] that can be used with the API.
+//
+// Only schema version 1.1 models are supported, and modular models (modules
+// and type extensions) are not. The model is only checked for syntax errors:
+// semantic errors, such as references to undefined types or relations, are
+// reported by the OpenFGA server when the model is created.
+func AuthModelFromDSL(data []byte) (*openfga.AuthorizationModel, error) {
+	model, err := parseDSL(strings.Split(string(data), "\n"))
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse DSL auth model: %v", err)
+	}
+	return model, nil
+}
+
+// parseDSL parses the given lines of an authorization model written in the
+// OpenFGA modeling language.
+func parseDSL(lines []string) (*openfga.AuthorizationModel, error) {
+	model := &openfga.AuthorizationModel{
+		TypeDefinitions: []openfga.TypeDefinition{},
+	}
+	conditions := make(map[string]openfga.Condition)
+	var seenModel, inRelations bool
+	for i := 0; i < len(lines); i++ {
+		lineNum := i + 1
+		line := strings.TrimSpace(stripDSLComment(lines[i]))
+		if line == "" {
+			continue
+		}
+		keyword, rest, _ := strings.Cut(line, " ")
+		rest = strings.TrimSpace(rest)
+		if !seenModel && keyword != "model" {
+			return nil, fmt.Errorf("line %d: expected model declaration, got %q", lineNum, line)
+		}
+		switch keyword {
+		case "model":
+			if seenModel || rest != "" {
+				return nil, fmt.Errorf("line %d: unexpected model declaration", lineNum)
+			}
+			seenModel = true
+		case "schema":
+			if model.SchemaVersion != "" || len(model.TypeDefinitions) > 0 {
+				return nil, fmt.Errorf("line %d: unexpected schema declaration", lineNum)
+			}
+			if rest != "1.1" {
+				return nil, fmt.Errorf("line %d: unsupported schema version %q", lineNum, rest)
+			}
+			model.SchemaVersion = rest
+		case "type":
+			if model.SchemaVersion == "" {
+				return nil, fmt.Errorf("line %d: missing schema declaration", lineNum)
+			}
+			if !isDSLIdentifier(rest) {
+				return nil, fmt.Errorf("line %d: invalid type name %q", lineNum, rest)
+			}
+			for _, td := range model.TypeDefinitions {
+				if td.Type == rest {
+					return nil, fmt.Errorf("line %d: type %q defined more than once", lineNum, rest)
+				}
+			}
+			model.TypeDefinitions = append(model.TypeDefinitions, openfga.TypeDefinition{
+				Type:      rest,
+				Relations: &map[string]openfga.Userset{},
+			})
+			inRelations = false
+		case "relations":
+			if len(model.TypeDefinitions) == 0 || inRelations || rest != "" {
+				return nil, fmt.Errorf("line %d: unexpected relations declaration", lineNum)
+			}
+			inRelations = true
+		case "define":
+			if !inRelations {
+				return nil, fmt.Errorf("line %d: unexpected relation definition", lineNum)
+			}
+			td := &model.TypeDefinitions[len(model.TypeDefinitions)-1]
+			if err := parseDSLRelation(td, rest); err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNum, err)
+			}
+		case "condition":
+			if model.SchemaVersion == "" {
+				return nil, fmt.Errorf("line %d: missing schema declaration", lineNum)
+			}
+			cond, err := parseDSLConditionHeader(rest)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNum, err)
+			}
+			if _, ok := conditions[cond.Name]; ok {
+				return nil, fmt.Errorf("line %d: condition %q defined more than once", lineNum, cond.Name)
+			}
+			// The condition expression spans all the lines up to the closing
+			// brace. Comments are not stripped from the expression, as "#" may
+			// be part of a string literal.
+			var expr []string
+			for i++; i < len(lines) && strings.TrimSpace(lines[i]) != "}"; i++ {
+				if l := strings.TrimSpace(lines[i]); l != "" {
+					expr = append(expr, l)
+				}
+			}
+			if i == len(lines) {
+				return nil, fmt.Errorf("line %d: condition %q is not closed", lineNum, cond.Name)
+			}
+			if len(expr) == 0 {
+				return nil, fmt.Errorf("line %d: condition %q has no expression", lineNum, cond.Name)
+			}
+			cond.Expression = strings.Join(expr, "\n")
+			conditions[cond.Name] = cond
+			inRelations = false
+		default:
+			return nil, fmt.Errorf("line %d: unexpected %q", lineNum, line)
+		}
+	}
+	if model.SchemaVersion == "" {
+		return nil, errors.New("missing schema declaration")
+	}
+	if len(conditions) > 0 {
+		model.Conditions = &conditions
+	}
+	return model, nil
+}
+
+// parseDSLRelation parses the given relation definition (for instance
+// "viewer: [user] or editor") and adds it to the given type definition.
+func parseDSLRelation(td *openfga.TypeDefinition, def string) error {
+	name, expr, ok := strings.Cut(def, ":")
+	name = strings.TrimSpace(name)
+	if !ok || !isDSLIdentifier(name) {
+		return fmt.Errorf("invalid relation definition %q", def)
+	}
+	relations := td.GetRelations()
+	if _, ok := relations[name]; ok {
+		return fmt.Errorf("relation %q defined more than once", name)
+	}
+	p := dslExprParser{tokens: tokenizeDSLExpr(expr)}
+	userset, err := p.parseExpr()
+	if err != nil {
+		return fmt.Errorf("invalid definition for relation %q: %v", name, err)
+	}
+	if tok := p.next(); tok != "" {
+		return fmt.Errorf("invalid definition for relation %q: unexpected %q", name, tok)
+	}
+	relations[name] = userset
+	td.Relations = &relations
+
+	if td.Metadata == nil {
+		td.Metadata = &openfga.Metadata{Relations: &map[string]openfga.RelationMetadata{}}
+	}
+	directTypes := p.directTypes
+	if directTypes == nil {
+		directTypes = []openfga.RelationReference{}
+	}
+	(*td.Metadata.Relations)[name] = openfga.RelationMetadata{
+		DirectlyRelatedUserTypes: &directTypes,
+	}
+	return nil
+}
+
+// parseDSLConditionHeader parses the given condition header (for instance
+// "in_office_hours(current_time: timestamp) {") into a condition without an
+// expression.
+func parseDSLConditionHeader(header string) (openfga.Condition, error) {
+	name, rest, ok := strings.Cut(header, "(")
+	name = strings.TrimSpace(name)
+	if !ok || !isDSLIdentifier(name) {
+		return openfga.Condition{}, fmt.Errorf("invalid condition declaration %q", header)
+	}
+	params, rest, ok := strings.Cut(rest, ")")
+	if !ok || strings.TrimSpace(rest) != "{" {
+		return openfga.Condition{}, fmt.Errorf("invalid condition declaration %q", header)
+	}
+	// OpenFGA requires conditions to declare at least one parameter.
+	if strings.TrimSpace(params) == "" {
+		return openfga.Condition{}, fmt.Errorf("condition %q must declare at least one parameter", name)
+	}
+	parameters := make(map[string]openfga.ConditionParamTypeRef)
+	for _, param := range strings.Split(params, ",") {
+		paramName, paramType, ok := strings.Cut(param, ":")
+		paramName = strings.TrimSpace(paramName)
+		if !ok || !isDSLIdentifier(paramName) {
+			return openfga.Condition{}, fmt.Errorf("invalid parameter %q in condition %q", strings.TrimSpace(param), name)
+		}
+		ref, err := parseDSLConditionParamType(strings.TrimSpace(paramType))
+		if err != nil {
+			return openfga.Condition{}, fmt.Errorf("invalid parameter %q in condition %q: %v", paramName, name, err)
+		}
+		parameters[paramName] = ref
+	}
+	return openfga.Condition{
+		Name:       name,
+		Parameters: &parameters,
+	}, nil
+}
+
+// parseDSLConditionParamType parses the type of a condition parameter, for
+// instance "string" or "map<int>".
+func parseDSLConditionParamType(s string) (openfga.ConditionParamTypeRef, error) {
+	name, generic, isGeneric := strings.Cut(s, "<")
+	typeName := openfga.TypeName("TYPE_NAME_" + strings.ToUpper(name))
+	if name == "" || name != strings.ToLower(name) || !typeName.IsValid() || typeName == openfga.TYPENAME_UNSPECIFIED {
+		return openfga.ConditionParamTypeRef{}, fmt.Errorf("invalid type %q", s)
+	}
+	ref := openfga.ConditionParamTypeRef{TypeName: typeName}
+	if isGeneric {
+		generic, ok := strings.CutSuffix(generic, ">")
+		if !ok {
+			return openfga.ConditionParamTypeRef{}, fmt.Errorf("invalid type %q", s)
+		}
+		genericRef, err := parseDSLConditionParamType(generic)
+		if err != nil {
+			return openfga.ConditionParamTypeRef{}, err
+		}
+		ref.GenericTypes = &[]openfga.ConditionParamTypeRef{genericRef}
+	}
+	return ref, nil
+}
+
+// dslExprParser parses the expression defining a relation.
+type dslExprParser struct {
+	tokens []string
+	pos    int
+	// directTypes holds the directly related user types found while parsing
+	// the expression.
+	directTypes []openfga.RelationReference
+}
+
+// next consumes and returns the next token, or an empty string if there are
+// no more tokens.
+func (p *dslExprParser) next() string {
+	tok := p.peek()
+	if tok != "" {
+		p.pos++
+	}
+	return tok
+}
+
+// peek returns the next token without consuming it, or an empty string if
+// there are no more tokens.
+func (p *dslExprParser) peek() string {
+	if p.pos == len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+// parseExpr parses either a sequence of operands joined by the same operator
+// ("or" or "and"), or an operand followed by an operand excluded with "but
+// not". Operators cannot be mixed without parentheses.
+func (p *dslExprParser) parseExpr() (openfga.Userset, error) {
+	operand, err := p.parseOperand()
+	if err != nil {
+		return openfga.Userset{}, err
+	}
+	operands := []openfga.Userset{operand}
+	var op string
+	for p.peek() == "or" || p.peek() == "and" {
+		tok := p.next()
+		if op != "" && tok != op {
+			return openfga.Userset{}, fmt.Errorf("cannot mix %q and %q without parentheses", op, tok)
+		}
+		op = tok
+		operand, err := p.parseOperand()
+		if err != nil {
+			return openfga.Userset{}, err
+		}
+		operands = append(operands, operand)
+	}
+	userset := operands[0]
+	switch op {
+	case "or":
+		userset = openfga.Userset{Union: &openfga.Usersets{Child: operands}}
+	case "and":
+		userset = openfga.Userset{Intersection: &openfga.Usersets{Child: operands}}
+	}
+	if p.peek() == "but" {
+		if op != "" {
+			return openfga.Userset{}, fmt.Errorf(`cannot mix %q and "but not" without parentheses`, op)
+		}
+		p.next()
+		if tok := p.next(); tok != "not" {
+			return openfga.Userset{}, fmt.Errorf(`expected "not" after "but", got %q`, tok)
+		}
+		subtract, err := p.parseOperand()
+		if err != nil {
+			return openfga.Userset{}, err
+		}
+		if tok := p.peek(); tok == "or" || tok == "and" {
+			return openfga.Userset{}, fmt.Errorf(`cannot mix "but not" and %q without parentheses`, tok)
+		}
+		userset = openfga.Userset{Difference: &openfga.Difference{
+			Base:     userset,
+			Subtract: subtract,
+		}}
+	}
+	return userset, nil
+}
+
+// parseOperand parses a parenthesized expression, a list of directly related
+// user types, a relation or a relation from another relation (for instance
+// "viewer from parent").
+func (p *dslExprParser) parseOperand() (openfga.Userset, error) {
+	tok := p.next()
+	switch {
+	case tok == "":
+		return openfga.Userset{}, errors.New("unexpected end of expression")
+	case tok == "(":
+		userset, err := p.parseExpr()
+		if err != nil {
+			return openfga.Userset{}, err
+		}
+		if tok := p.next(); tok != ")" {
+			return openfga.Userset{}, fmt.Errorf(`expected ")", got %q`, tok)
+		}
+		return userset, nil
+	case tok == "[":
+		if p.directTypes != nil {
+			return openfga.Userset{}, errors.New("directly related user types specified more than once")
+		}
+		directTypes, err := p.parseDirectTypes()
+		if err != nil {
+			return openfga.Userset{}, err
+		}
+		p.directTypes = directTypes
+		return openfga.Userset{This: &map[string]interface{}{}}, nil
+	case !isDSLIdentifier(tok):
+		return openfga.Userset{}, fmt.Errorf("unexpected %q", tok)
+	}
+	if p.peek() != "from" {
+		return openfga.Userset{ComputedUserset: &openfga.ObjectRelation{
+			Relation: openfga.PtrString(tok),
+		}}, nil
+	}
+	p.next()
+	tupleset := p.next()
+	if !isDSLIdentifier(tupleset) {
+		return openfga.Userset{}, fmt.Errorf(`expected relation after "from", got %q`, tupleset)
+	}
+	return openfga.Userset{TupleToUserset: &openfga.TupleToUserset{
+		Tupleset:        openfga.ObjectRelation{Relation: openfga.PtrString(tupleset)},
+		ComputedUserset: openfga.ObjectRelation{Relation: openfga.PtrString(tok)},
+	}}, nil
+}
+
+// parseDirectTypes parses a list of directly related user types, for instance
+// "user, user:*, group#member, user with condition]". The opening bracket
+// must have already been consumed.
+func (p *dslExprParser) parseDirectTypes() ([]openfga.RelationReference, error) {
+	var refs []openfga.RelationReference
+	for {
+		tok := p.next()
+		var ref openfga.RelationReference
+		if kind, ok := strings.CutSuffix(tok, ":*"); ok {
+			ref.Type = kind
+			ref.Wildcard = &map[string]interface{}{}
+		} else if kind, relation, ok := strings.Cut(tok, "#"); ok {
+			if !isDSLIdentifier(relation) {
+				return nil, fmt.Errorf("invalid user type %q", tok)
+			}
+			ref.Type = kind
+			ref.Relation = openfga.PtrString(relation)
+		} else {
+			ref.Type = tok
+		}
+		if !isDSLIdentifier(ref.Type) {
+			return nil, fmt.Errorf("invalid user type %q", tok)
+		}
+		if p.peek() == "with" {
+			p.next()
+			condition := p.next()
+			if !isDSLIdentifier(condition) {
+				return nil, fmt.Errorf(`expected condition after "with", got %q`, condition)
+			}
+			ref.Condition = openfga.PtrString(condition)
+		}
+		refs = append(refs, ref)
+		switch tok := p.next(); tok {
+		case ",":
+		case "]":
+			return refs, nil
+		default:
+			return nil, fmt.Errorf(`expected "," or "]", got %q`, tok)
+		}
+	}
+}
+
+// dslKeywords holds the keywords used in relation definitions.
+var dslKeywords = map[string]bool{
+	"or":   true,
+	"and":  true,
+	"but":  true,
+	"not":  true,
+	"from": true,
+	"with": true,
+}
+
+// isDSLIdentifier reports whether s is a valid type, relation, condition or
+// parameter name.
+func isDSLIdentifier(s string) bool {
+	if s == "" || dslKeywords[s] {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// tokenizeDSLExpr splits a relation definition into tokens. Brackets,
+// parentheses and commas are returned as separate tokens.
+func tokenizeDSLExpr(expr string) []string {
+	var tokens []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			tokens = append(tokens, current.String())
+			current.Reset()
+		}
+	}
+	for _, r := range expr {
+		switch {
+		case strings.ContainsRune("[](),", r):
+			flush()
+			tokens = append(tokens, string(r))
+		case r == ' ' || r == '\t' || r == '\r':
+			flush()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush()
+	return tokens
+}
+
+// stripDSLComment removes the comment from the given line, if present. A
+// comment starts with a "#" at the beginning of the line or preceded by a
+// space, so that "#" can be used in user types such as "group#member".
+func stripDSLComment(line string) string {
+	for i, r := range line {
+		if r == '#' && (strings.TrimSpace(line[:i]) == "" || line[i-1] == ' ' || line[i-1] == '\t') {
+			return line[:i]
+		}
+	}
+	return line
+}
@@ -0,0 +1,373 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the LGPL license, see LICENSE file for details.
+
+package ofga_test
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	openfga "github.com/openfga/go-sdk"
+
+	"github.com/canonical/ofga"
+)
+
+var fullAuthModelJson = []byte(`{
+  "schema_version": "1.1",
+  "type_definitions": [
+    {"type": "user"},
+    {
+      "type": "group",
+      "relations": {"member": {"this": {}}},
+      "metadata": {"relations": {"member": {"directly_related_user_types": [
+        {"type": "user"},
+        {"type": "group", "relation": "member"}
+      ]}}}
+    },
+    {
+      "type": "document",
+      "relations": {
+        "parent": {"this": {}},
+        "owner": {"this": {}},
+        "blocked": {"this": {}},
+        "editor": {
+          "union": {"child": [
+            {"this": {}},
+            {"computedUserset": {"relation": "owner"}}
+          ]}
+        },
+        "viewer": {
+          "difference": {
+            "base": {
+              "union": {"child": [
+                {"this": {}},
+                {"computedUserset": {"relation": "editor"}},
+                {"tupleToUserset": {
+                  "tupleset": {"relation": "parent"},
+                  "computedUserset": {"relation": "viewer"}
+                }}
+              ]}
+            },
+            "subtract": {"computedUserset": {"relation": "blocked"}}
+          }
+        },
+        "auditor": {
+          "intersection": {"child": [
+            {"computedUserset": {"relation": "viewer"}},
+            {"computedUserset": {"relation": "editor"}}
+          ]}
+        }
+      },
+      "metadata": {"relations": {
+        "parent": {"directly_related_user_types": [{"type": "document"}]},
+        "owner": {"directly_related_user_types": [{"type": "user"}]},
+        "blocked": {"directly_related_user_types": [{"type": "user"}]},
+        "editor": {"directly_related_user_types": [
+          {"type": "user", "condition": "in_office_hours"},
+          {"type": "group", "relation": "member"}
+        ]},
+        "viewer": {"directly_related_user_types": [
+          {"type": "user"},
+          {"type": "user", "wildcard": {}}
+        ]}
+      }}
+    }
+  ],
+  "conditions": {
+    "in_office_hours": {
+      "name": "in_office_hours",
+      "expression": "current_time.getHours() >= 9 && current_time.getHours() < 17",
+      "parameters": {
+        "current_time": {"type_name": "TYPE_NAME_TIMESTAMP"},
+        "allowed": {"type_name": "TYPE_NAME_LIST", "generic_types": [{"type_name": "TYPE_NAME_STRING"}]}
+      }
+    }
+  }
+}`)
+
+// authModelConditions holds the conditions defined in fullAuthModelJson.
+var authModelConditions = map[string]openfga.Condition{
+	"in_office_hours": {
+		Name:       "in_office_hours",
+		Expression: "current_time.getHours() >= 9 && current_time.getHours() < 17",
+		Parameters: &map[string]openfga.ConditionParamTypeRef{
+			"current_time": {TypeName: openfga.TYPENAME_TIMESTAMP},
+			"allowed": {
+				TypeName:     openfga.TYPENAME_LIST,
+				GenericTypes: &[]openfga.ConditionParamTypeRef{{TypeName: openfga.TYPENAME_STRING}},
+			},
+		},
+	},
+}
+
+// fullAuthModelDSL holds the DSL representation of fullAuthModelJson.
+const fullAuthModelDSL = `model
+  schema 1.1
+
+type user
+
+type group
+  relations
+    define member: [user, group#member]
+
+type document
+  relations
+    define auditor: viewer and editor
+    define blocked: [user]
+    define editor: [user with in_office_hours, group#member] or owner
+    define owner: [user]
+    define parent: [document]
+    define viewer: ([user, user:*] or editor or viewer from parent) but not blocked
+
+condition in_office_hours(allowed: list<string>, current_time: timestamp) {
+  current_time.getHours() >= 9 && current_time.getHours() < 17
+}
+`
+
+func TestAuthModelToDSL(t *testing.T) {
+	c := qt.New(t)
+
+	fullAuthModel, err := ofga.AuthModelFromJSON(fullAuthModelJson)
+	c.Assert(err, qt.IsNil)
+
+	tests := []struct {
+		about       string
+		model       openfga.AuthorizationModel
+		expectedDSL string
+		expectedErr string
+	}{{
+		about: "simple model is rendered successfully",
+		model: authModel,
+		expectedDSL: `model
+  schema 1.1
+
+type user
+
+type document
+  relations
+    define viewer: [user] or writer
+    define writer: [user]
+`,
+	}, {
+		about:       "model using all supported constructs is rendered successfully",
+		model:       *fullAuthModel,
+		expectedDSL: fullAuthModelDSL,
+	}, {
+		about:       "unsupported schema version returns an error",
+		model:       openfga.AuthorizationModel{SchemaVersion: "1.0"},
+		expectedErr: `unsupported schema version "1.0"`,
+	}, {
+		about: "empty userset returns an error",
+		model: openfga.AuthorizationModel{
+			SchemaVersion: "1.1",
+			TypeDefinitions: []openfga.TypeDefinition{{
+				Type:      "document",
+				Relations: &map[string]openfga.Userset{"viewer": {}},
+			}},
+		},
+		expectedErr: "cannot render relation document#viewer: empty userset",
+	}}
+
+	for _, test := range tests {
+		test := test
+		c.Run(test.about, func(c *qt.C) {
+			c.Parallel()
+
+			dsl, err := ofga.AuthModelToDSL(test.model)
+			if test.expectedErr != "" {
+				c.Assert(err, qt.ErrorMatches, test.expectedErr)
+				c.Assert(dsl, qt.Equals, "")
+			} else {
+				c.Assert(err, qt.IsNil)
+				c.Assert(dsl, qt.Equals, test.expectedDSL)
+			}
+		})
+	}
+}
+
+func TestAuthModelFromDSL(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		about             string
+		dsl               string
+		expectedAuthModel *openfga.AuthorizationModel
+		expectedErr       string
+	}{{
+		about: "model is parsed successfully",
+		dsl: `
+# The document model.
+model
+  schema 1.1
+
+type user
+
+type document
+  relations
+    define writer: [user, group#member with in_office_hours] # Writers.
+    define viewer: ([user:*] or writer) but not blocked
+    define blocked: writer from parent
+    define auditor: viewer and writer
+
+condition in_office_hours(current_time: timestamp, days: list<int>) {
+  current_time.getHours() >= 9 &&
+  current_time.getDayOfWeek() in days
+}
+`,
+		expectedAuthModel: &openfga.AuthorizationModel{
+			SchemaVersion: "1.1",
+			TypeDefinitions: []openfga.TypeDefinition{
+				{Type: "user", Relations: &map[string]openfga.Userset{}},
+				{
+					Type: "document",
+					Relations: &map[string]openfga.Userset{
+						"writer": {This: &map[string]interface{}{}},
+						"viewer": {Difference: &openfga.Difference{
+							Base: openfga.Userset{Union: &openfga.Usersets{
+								Child: []openfga.Userset{
+									{This: &map[string]interface{}{}},
+									{ComputedUserset: &openfga.ObjectRelation{
+										Relation: openfga.PtrString("writer"),
+									}},
+								},
+							}},
+							Subtract: openfga.Userset{ComputedUserset: &openfga.ObjectRelation{
+								Relation: openfga.PtrString("blocked"),
+							}},
+						}},
+						"blocked": {TupleToUserset: &openfga.TupleToUserset{
+							Tupleset:        openfga.ObjectRelation{Relation: openfga.PtrString("parent")},
+							ComputedUserset: openfga.ObjectRelation{Relation: openfga.PtrString("writer")},
+						}},
+						"auditor": {Intersection: &openfga.Usersets{
+							Child: []openfga.Userset{
+								{ComputedUserset: &openfga.ObjectRelation{
+									Relation: openfga.PtrString("viewer"),
+								}},
+								{ComputedUserset: &openfga.ObjectRelation{
+									Relation: openfga.PtrString("writer"),
+								}},
+							},
+						}},
+					},
+					Metadata: &openfga.Metadata{
+						Relations: &map[string]openfga.RelationMetadata{
+							"writer": {
+								DirectlyRelatedUserTypes: &[]openfga.RelationReference{
+									{Type: "user"},
+									{
+										Type:      "group",
+										Relation:  openfga.PtrString("member"),
+										Condition: openfga.PtrString("in_office_hours"),
+									},
+								},
+							},
+							"viewer": {
+								DirectlyRelatedUserTypes: &[]openfga.RelationReference{
+									{Type: "user", Wildcard: &map[string]interface{}{}},
+								},
+							},
+							"blocked": {
+								DirectlyRelatedUserTypes: &[]openfga.RelationReference{},
+							},
+							"auditor": {
+								DirectlyRelatedUserTypes: &[]openfga.RelationReference{},
+							},
+						},
+					},
+				},
+			},
+			Conditions: &map[string]openfga.Condition{
+				"in_office_hours": {
+					Name:       "in_office_hours",
+					Expression: "current_time.getHours() >= 9 &&\ncurrent_time.getDayOfWeek() in days",
+					Parameters: &map[string]openfga.ConditionParamTypeRef{
+						"current_time": {TypeName: openfga.TYPENAME_TIMESTAMP},
+						"days": {
+							TypeName:     openfga.TYPENAME_LIST,
+							GenericTypes: &[]openfga.ConditionParamTypeRef{{TypeName: openfga.TYPENAME_INT}},
+						},
+					},
+				},
+			},
+		},
+	}, {
+		about:       "missing model declaration",
+		dsl:         "type user",
+		expectedErr: `cannot parse DSL auth model: line 1: expected model declaration, got "type user"`,
+	}, {
+		about:       "missing schema declaration",
+		dsl:         "model\ntype user",
+		expectedErr: "cannot parse DSL auth model: line 2: missing schema declaration",
+	}, {
+		about:       "unsupported schema version",
+		dsl:         "model\n  schema 1.0",
+		expectedErr: `cannot parse DSL auth model: line 2: unsupported schema version "1.0"`,
+	}, {
+		about:       "relation defined outside of a type",
+		dsl:         "model\n  schema 1.1\n  define viewer: [user]",
+		expectedErr: "cannot parse DSL auth model: line 3: unexpected relation definition",
+	}, {
+		about:       "relation defined more than once",
+		dsl:         "model\n  schema 1.1\ntype doc\n  relations\n    define viewer: [user]\n    define viewer: [user]",
+		expectedErr: `cannot parse DSL auth model: line 6: relation "viewer" defined more than once`,
+	}, {
+		about:       "operators mixed without parentheses",
+		dsl:         "model\n  schema 1.1\ntype doc\n  relations\n    define viewer: [user] or editor and owner",
+		expectedErr: `cannot parse DSL auth model: line 5: invalid definition for relation "viewer": cannot mix "or" and "and" without parentheses`,
+	}, {
+		about:       "but not mixed with or without parentheses",
+		dsl:         "model\n  schema 1.1\ntype doc\n  relations\n    define viewer: [user] or editor but not blocked",
+		expectedErr: `cannot parse DSL auth model: line 5: invalid definition for relation "viewer": cannot mix "or" and "but not" without parentheses`,
+	}, {
+		about:       "and mixed with but not without parentheses",
+		dsl:         "model\n  schema 1.1\ntype doc\n  relations\n    define viewer: [user] but not blocked and editor",
+		expectedErr: `cannot parse DSL auth model: line 5: invalid definition for relation "viewer": cannot mix "but not" and "and" without parentheses`,
+	}, {
+		about:       "unclosed parenthesis",
+		dsl:         "model\n  schema 1.1\ntype doc\n  relations\n    define viewer: ([user] or editor",
+		expectedErr: `cannot parse DSL auth model: line 5: invalid definition for relation "viewer": expected "\)", got ""`,
+	}, {
+		about:       "unexpected token after the expression",
+		dsl:         "model\n  schema 1.1\ntype doc\n  relations\n    define viewer: editor owner",
+		expectedErr: `cannot parse DSL auth model: line 5: invalid definition for relation "viewer": unexpected "owner"`,
+	}, {
+		about:       "invalid condition parameter type",
+		dsl:         "model\n  schema 1.1\ncondition c(x: float) {\n  x > 1\n}",
+		expectedErr: `cannot parse DSL auth model: line 3: invalid parameter "x" in condition "c": invalid type "float"`,
+	}, {
+		about:       "condition without parameters",
+		dsl:         "model\n  schema 1.1\ncondition c() {\n  true\n}",
+		expectedErr: `cannot parse DSL auth model: line 3: condition "c" must declare at least one parameter`,
+	}, {
+		about:       "unclosed condition",
+		dsl:         "model\n  schema 1.1\ncondition c(x: int) {\n  x > 1",
+		expectedErr: `cannot parse DSL auth model: line 3: condition "c" is not closed`,
+	}}
+
+	for _, test := range tests {
+		test := test
+		c.Run(test.about, func(c *qt.C) {
+			c.Parallel()
+
+			model, err := ofga.AuthModelFromDSL([]byte(test.dsl))
+			if test.expectedErr != "" {
+				c.Assert(err, qt.ErrorMatches, test.expectedErr)
+				c.Assert(model, qt.IsNil)
+			} else {
+				c.Assert(err, qt.IsNil)
+				c.Assert(model, qt.DeepEquals, test.expectedAuthModel)
+			}
+		})
+	}
+}
+
+func TestAuthModelDSLRoundTrip(t *testing.T) {
+	c := qt.New(t)
+
+	model, err := ofga.AuthModelFromDSL([]byte(fullAuthModelDSL))
+	c.Assert(err, qt.IsNil)
+	dsl, err := ofga.AuthModelToDSL(*model)
+	c.Assert(err, qt.IsNil)
+	c.Assert(dsl, qt.Equals, fullAuthModelDSL)
+}
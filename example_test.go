@@ -8,7 +8,9 @@ import (
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
+	openfga "github.com/openfga/go-sdk"
         "github.com/openfga/go-sdk/telemetry"
 
 	"github.com/canonical/ofga"
@@ -42,6 +44,27 @@ func ExampleParseEntity_entitySet() {
 	// {Kind:organization ID:canonical Relation:member} <nil>
 }
 
+func ExampleNewEntity() {
+	entity, err := ofga.NewEntity("organization", "canonical")
+	fmt.Printf("%+v %v", entity, err)
+	// Output:
+	// {Kind:organization ID:canonical Relation:} <nil>
+}
+
+func ExampleNewEntitySet() {
+	entity, err := ofga.NewEntitySet("organization", "canonical", "member")
+	fmt.Printf("%+v %v", entity, err)
+	// Output:
+	// {Kind:organization ID:canonical Relation:member} <nil>
+}
+
+func ExampleParseTuple() {
+	tuple, err := ofga.ParseTuple("user:alice", "member", "organization:canonical")
+	fmt.Printf("%+v %+v %v", *tuple.Object, *tuple.Target, err)
+	// Output:
+	// {Kind:user ID:alice Relation:} {Kind:organization ID:canonical Relation:} <nil>
+}
+
 func ExampleNewClient() {
 	client, err := ofga.NewClient(context.Background(), ofga.OpenFGAParams{
 		Scheme:      os.Getenv("OPENFGA_API_SCHEME"), // defaults to `https` if not specified.
@@ -75,6 +98,20 @@ func ExampleNewClient_telemetry() {
 	fmt.Print(client.AuthModelID())
 }
 
+func ExampleNewClientWithNewStore() {
+	client, storeID, err := ofga.NewClientWithNewStore(context.Background(), ofga.OpenFGAParams{
+		Scheme: os.Getenv("OPENFGA_API_SCHEME"), // defaults to `https` if not specified.
+		Host:   os.Getenv("OPENFGA_API_HOST"),
+		Port:   os.Getenv("OPENFGA_API_PORT"),
+		Token:  os.Getenv("SECRET_TOKEN"), // Optional, based on the OpenFGA instance configuration.
+	}, "MyStore")
+	if err != nil {
+		// Handle err
+		return
+	}
+	fmt.Print(storeID == client.StoreID())
+}
+
 func ExampleClient_AddRelation() {
 	// Add a relationship tuple
 	err := client.AddRelation(context.Background(), ofga.Tuple{
@@ -113,6 +150,19 @@ func ExampleClient_AddRelation_multiple() {
 	}
 }
 
+func ExampleClient_AddRelationSafe() {
+	// Add a relationship tuple, succeeding even if it already exists
+	err := client.AddRelationSafe(context.Background(), ofga.Tuple{
+		Object:   &ofga.Entity{Kind: "user", ID: "123"},
+		Relation: "editor",
+		Target:   &ofga.Entity{Kind: "document", ID: "ABC"},
+	})
+	if err != nil {
+		// Handle err
+		return
+	}
+}
+
 func ExampleClient_CheckRelation() {
 	// Check if the relation exists
 	allowed, err := client.CheckRelation(context.Background(), ofga.Tuple{
@@ -149,6 +199,21 @@ func ExampleClient_CheckRelation_contextualTuples() {
 	fmt.Printf("allowed: %v", allowed)
 }
 
+func ExampleClient_CheckRelations() {
+	// Check which of the relations exist
+	allowed, err := client.CheckRelations(
+		context.Background(),
+		ofga.Entity{Kind: "user", ID: "123"},
+		[]ofga.Relation{"viewer", "editor", "owner"},
+		ofga.Entity{Kind: "document", ID: "ABC"},
+	)
+	if err != nil {
+		// Handle err
+		return
+	}
+	fmt.Printf("can edit: %v", allowed["editor"])
+}
+
 func ExampleClient_CheckRelationWithTracing() {
 	// Check if the relation exists
 	allowed, err := client.CheckRelationWithTracing(context.Background(), ofga.Tuple{
@@ -163,6 +228,69 @@ func ExampleClient_CheckRelationWithTracing() {
 	fmt.Printf("allowed: %v", allowed)
 }
 
+func ExampleClient_AddRelationIfAbsent() {
+	created, err := client.AddRelationIfAbsent(context.Background(), ofga.Tuple{
+		Object:   &ofga.Entity{Kind: "user", ID: "123"},
+		Relation: "editor",
+		Target:   &ofga.Entity{Kind: "document", ID: "ABC"},
+	})
+	if err != nil {
+		// Handle err
+		return
+	}
+	if created {
+		fmt.Println("access granted")
+	} else {
+		fmt.Println("user already had access")
+	}
+}
+
+func ExampleClient_SetRelation() {
+	// Make user:123 an editor of document:ABC, as requested in a user
+	// interface toggle.
+	err := client.SetRelation(context.Background(), ofga.Tuple{
+		Object:   &ofga.Entity{Kind: "user", ID: "123"},
+		Relation: "editor",
+		Target:   &ofga.Entity{Kind: "document", ID: "ABC"},
+	}, true)
+	if err != nil {
+		// Handle err
+		return
+	}
+}
+
+func ExampleClient_WaitForRelation() {
+	tuple := ofga.Tuple{
+		Object:   &ofga.Entity{Kind: "user", ID: "123"},
+		Relation: "editor",
+		Target:   &ofga.Entity{Kind: "document", ID: "ABC"},
+	}
+	if err := client.AddRelation(context.Background(), tuple); err != nil {
+		// Handle err
+		return
+	}
+
+	// Wait for the relation to be visible to checks.
+	if err := client.WaitForRelation(context.Background(), tuple, true, 5*time.Second); err != nil {
+		// Handle err
+		return
+	}
+}
+
+func ExampleClient_CheckRelationTrace() {
+	// Check if the relation exists and find out how it was resolved.
+	allowed, resolution, err := client.CheckRelationTrace(context.Background(), ofga.Tuple{
+		Object:   &ofga.Entity{Kind: "user", ID: "123"},
+		Relation: "editor",
+		Target:   &ofga.Entity{Kind: "document", ID: "ABC"},
+	})
+	if err != nil {
+		// Handle err
+		return
+	}
+	fmt.Printf("allowed: %v, resolution: %s", allowed, resolution)
+}
+
 func ExampleClient_CheckRelationWithTracing_contextualTuples() {
 	contextualTuples := []ofga.Tuple{{
 		Object:   &ofga.Entity{Kind: "user", ID: "123"},
@@ -185,6 +313,23 @@ func ExampleClient_CheckRelationWithTracing_contextualTuples() {
 	fmt.Printf("allowed: %v", allowed)
 }
 
+func ExampleNewCachingClient() {
+	// Cache up to 1000 check results for 10 seconds each
+	cachingClient := ofga.NewCachingClient(client, 10*time.Second, 1000)
+
+	// Check if the relation exists, the result is cached for later calls
+	allowed, err := cachingClient.CheckRelation(context.Background(), ofga.Tuple{
+		Object:   &ofga.Entity{Kind: "user", ID: "123"},
+		Relation: "viewer",
+		Target:   &ofga.Entity{Kind: "document", ID: "ABC"},
+	})
+	if err != nil {
+		// Handle err
+		return
+	}
+	fmt.Println(allowed)
+}
+
 func ExampleClient_RemoveRelation() {
 	// Remove a relationship tuple
 	err := client.RemoveRelation(context.Background(), ofga.Tuple{
@@ -354,6 +499,104 @@ func ExampleClient_CreateAuthModel() {
 	fmt.Println(authModelID)
 }
 
+func ExampleClient_CreateAuthModelFromFile() {
+	// Create an auth model in OpenFGA from a file containing either its JSON
+	// representation or its definition in the modeling language
+	authModelID, err := client.CreateAuthModelFromFile(context.Background(), "model.fga")
+	if err != nil {
+		// Handle error
+	}
+	fmt.Println(authModelID)
+}
+
+func ExampleAuthModelFromDSL() {
+	// Assume we have the following auth model written in the modeling language
+	dsl := []byte(`model
+  schema 1.1
+
+type user
+
+type document
+  relations
+    define viewer: [user]
+`)
+
+	// Convert the model into internal representation
+	model, err := ofga.AuthModelFromDSL(dsl)
+	if err != nil {
+		// Handle err
+	}
+
+	// Create an auth model in OpenFGA using the internal representation
+	authModelID, err := client.CreateAuthModel(context.Background(), model)
+	if err != nil {
+		// Handle error
+	}
+	fmt.Println(authModelID)
+}
+
+func ExampleClient_CreateAuthModelValidated() {
+	// Assume we have the following json auth model
+	json := []byte(`{
+	  "type_definitions": [
+		{
+		  "type": "user",
+		  "relations": {}
+		},
+		{
+		  "type": "document",
+		  "relations": {
+			"viewer": {
+			  "this": {}
+			}
+		  },
+		  "metadata": {
+			"relations": {
+			  "viewer": {
+				"directly_related_user_types": [
+				  {
+					"type": "user"
+				  }
+				]
+			  }
+			}
+		  }
+		}
+	  ],
+	  "schema_version": "1.1"
+	}`)
+
+	// Convert json into internal representation
+	model, err := ofga.AuthModelFromJSON(json)
+	if err != nil {
+		// Handle err
+	}
+
+	// Create the auth model and verify that it behaves as expected
+	authModelID, err := client.CreateAuthModelValidated(context.Background(), model, []openfga.Assertion{{
+		TupleKey: openfga.AssertionTupleKey{
+			User:     "user:123",
+			Relation: "viewer",
+			Object:   "document:ABC",
+		},
+		Expectation: false,
+	}})
+	if err != nil {
+		// Handle error, the model should not be used
+	}
+	fmt.Println(authModelID)
+}
+
+func ExampleClient_WriteAssertionsFromFile() {
+	// Write the assertions defined in the file (one JSON object per line,
+	// e.g. {"tuple": {"user": "user:alice", "relation": "viewer", "object":
+	// "document:1"}, "expected": true}) for the given auth model
+	err := client.WriteAssertionsFromFile(context.Background(), "ABC1234", "assertions.ndjson")
+	if err != nil {
+		// Handle error
+	}
+}
+
 func ExampleClient_ListAuthModels() {
 	// Fetch a list of auth models using the default page size
 	resp, err := client.ListAuthModels(context.Background(), 0, "")
@@ -380,6 +623,38 @@ func ExampleClient_ListAuthModels() {
 	}
 }
 
+func ExampleClient_ListAllAuthModels() {
+	// Fetch all authorization models in the store, newest first
+	models, err := client.ListAllAuthModels(context.Background())
+	if err != nil {
+		// Handle error
+	}
+
+	for _, model := range models {
+		fmt.Println(model.GetId(), model.GetSchemaVersion())
+	}
+}
+
+func ExampleClient_FindAuthModelsBySchemaVersion() {
+	// Find all the auth models using schema version 1.1
+	models, err := client.FindAuthModelsBySchemaVersion(context.Background(), "1.1")
+	if err != nil {
+		// Handle error
+	}
+	for _, model := range models {
+		fmt.Println(model.GetId())
+	}
+}
+
+func ExampleClient_StoreSummary() {
+	// Summarize the content of the store.
+	summary, err := client.StoreSummary(context.Background())
+	if err != nil {
+		// Handle error
+	}
+	fmt.Printf("store %s has %d models, latest: %s (schema %s)", summary.StoreName, summary.AuthModelCount, summary.LatestAuthModelID, summary.LatestSchemaVersion)
+}
+
 func ExampleClient_GetAuthModel() {
 	// fetch an auth model by ID
 	model, err := client.GetAuthModel(context.Background(), "ABC1234")
@@ -389,6 +664,57 @@ func ExampleClient_GetAuthModel() {
 	}
 }
 
+func ExampleClient_GetAuthModelAsDSL() {
+	// Fetch an auth model by ID and render it using the modeling language
+	dsl, err := client.GetAuthModelAsDSL(context.Background(), "ABC1234")
+	if err != nil {
+		// Handle error
+	}
+	fmt.Println(dsl)
+}
+
+func ExampleAuthModelToDSL() {
+	model, err := ofga.AuthModelFromJSON([]byte(`{
+		"schema_version": "1.1",
+		"type_definitions": [
+			{"type": "user"},
+			{
+				"type": "document",
+				"relations": {"viewer": {"this": {}}},
+				"metadata": {"relations": {"viewer": {"directly_related_user_types": [{"type": "user"}]}}}
+			}
+		]
+	}`))
+	if err != nil {
+		// Handle error
+	}
+	dsl, err := ofga.AuthModelToDSL(*model)
+	if err != nil {
+		// Handle error
+	}
+	fmt.Print(dsl)
+	// Output:
+	// model
+	//   schema 1.1
+	//
+	// type user
+	//
+	// type document
+	//   relations
+	//     define viewer: [user]
+}
+
+func ExampleClient_AssertModelHasRelations() {
+	// Ensure the authorization model defines the relations used by the service
+	err := client.AssertModelHasRelations(context.Background(), map[ofga.Kind][]ofga.Relation{
+		"user":     nil,
+		"document": {"viewer", "editor", "owner"},
+	})
+	if err != nil {
+		// Handle error, e.g. refuse to start the service
+	}
+}
+
 func ExampleClient_FindMatchingTuples() {
 	// Find all tuples where bob is a writer of a document
 	searchTuple := ofga.Tuple{
@@ -421,6 +747,50 @@ func ExampleClient_FindMatchingTuples() {
 	}
 }
 
+func ExampleClient_FindMatchingTuplesAnyRelation() {
+	// Find all tuples where bob is either an editor or an owner of a document
+	tuples, err := client.FindMatchingTuplesAnyRelation(
+		context.Background(),
+		ofga.Entity{Kind: "user", ID: "bob"},
+		ofga.Entity{Kind: "document"},
+		[]ofga.Relation{"editor", "owner"},
+		0,
+	)
+	if err != nil {
+		// Handle error
+	}
+
+	for _, tuple := range tuples {
+		// Process the matching tuples
+		fmt.Println(tuple)
+	}
+}
+
+func ExampleClient_FindTuplesReferencing() {
+	// Remove all the tuples referencing a deleted document.
+	tuples, err := client.FindTuplesReferencing(context.Background(), ofga.Entity{Kind: "document", ID: "ABC"})
+	if err != nil {
+		// Handle error
+	}
+	if len(tuples) == 0 {
+		return
+	}
+	if err := client.RemoveRelation(context.Background(), ofga.StripTimestamps(tuples)...); err != nil {
+		// Handle error
+	}
+}
+
+func ExampleClient_FindUserGrants() {
+	// Find all the documents user 123 can access, grouped by relation.
+	grants, err := client.FindUserGrants(context.Background(), ofga.Entity{Kind: "user", ID: "123"}, "document")
+	if err != nil {
+		// Handle error
+	}
+	for relation, documents := range grants {
+		fmt.Printf("%s: %v\n", relation, documents)
+	}
+}
+
 func ExampleClient_FindUsersByRelation() {
 	// Find all users that have the viewer relation with document ABC, expanding
 	// matching user sets upto two levels deep only.
@@ -439,6 +809,23 @@ func ExampleClient_FindUsersByRelation() {
 	}
 }
 
+func ExampleClient_ExpandTree() {
+	// Get the tree describing how the viewer relation with document ABC is
+	// derived.
+	searchTuple := ofga.Tuple{
+		Relation: "viewer",
+		Target:   &ofga.Entity{Kind: "document", ID: "ABC"},
+	}
+	tree, err := client.ExpandTree(context.Background(), searchTuple)
+	if err != nil {
+		// Handle error
+	}
+
+	// Process the tree, starting from its root.
+	root := tree.GetRoot()
+	fmt.Println(root.GetName())
+}
+
 func ExampleClient_FindAccessibleObjectsByRelation() {
 	// Find all documents that the user bob can view by virtue of direct or
 	// implied relationships.
@@ -457,3 +844,155 @@ func ExampleClient_FindAccessibleObjectsByRelation() {
 		fmt.Println(doc)
 	}
 }
+
+func ExampleClient_FindAccessibleObjectIDsByRelation() {
+	// Find all documents that the user bob can view, without parsing them
+	objects, err := client.FindAccessibleObjectIDsByRelation(context.Background(), ofga.Tuple{
+		Object:   &ofga.Entity{Kind: "user", ID: "bob"},
+		Relation: "viewer",
+		Target:   &ofga.Entity{Kind: "document"},
+	})
+	if err != nil {
+		// Handle error
+	}
+
+	for _, object := range objects {
+		// Process the objects (e.g. "document:planning")
+		fmt.Println(object)
+	}
+}
+
+func ExampleClient_FindAccessibleObjectsByRelationWithLimit() {
+	// Find at most 10 documents that the user bob can view by virtue of
+	// direct or implied relationships.
+	searchTuple := ofga.Tuple{
+		Object:   &ofga.Entity{Kind: "user", ID: "bob"},
+		Relation: "viewer",
+		Target:   &ofga.Entity{Kind: "document"},
+	}
+	docs, err := client.FindAccessibleObjectsByRelationWithLimit(context.Background(), searchTuple, 10)
+	if err != nil {
+		// Handle error
+	}
+
+	for _, doc := range docs {
+		// Process the matching documents
+		fmt.Println(doc)
+	}
+}
+
+func ExampleClient_ListUsers() {
+	// Find all users that can view the document ABC, taking into account a
+	// temporary grant and the context of the current request.
+	searchTuple := ofga.Tuple{
+		Object:   &ofga.Entity{Kind: "user"},
+		Relation: "viewer",
+		Target:   &ofga.Entity{Kind: "document", ID: "ABC"},
+	}
+	users, err := client.ListUsers(context.Background(), searchTuple, ofga.ListUsersOptions{
+		ContextualTuples: []ofga.Tuple{{
+			Object:   &ofga.Entity{Kind: "user", ID: "bob"},
+			Relation: "viewer",
+			Target:   &ofga.Entity{Kind: "document", ID: "ABC"},
+		}},
+		Context: map[string]any{"current_time": "2023-01-01T10:00:00Z"},
+	})
+	if err != nil {
+		// Handle error
+	}
+
+	for _, user := range users {
+		// Process the users
+		fmt.Println(user)
+	}
+}
+
+func ExampleClient_ExportTuples() {
+	// Export all tuples in the store to a file
+	f, err := os.Create("tuples.ndjson")
+	if err != nil {
+		// Handle error
+		return
+	}
+	defer f.Close()
+
+	count, err := client.ExportTuples(context.Background(), f)
+	if err != nil {
+		// Handle error
+	}
+	fmt.Println(count)
+}
+
+func ExampleClient_ImportTuples() {
+	// Import tuples previously exported using ExportTuples
+	f, err := os.Open("tuples.ndjson")
+	if err != nil {
+		// Handle error
+		return
+	}
+	defer f.Close()
+
+	count, err := client.ImportTuples(context.Background(), f, 100)
+	if err != nil {
+		// Handle error
+	}
+	fmt.Println(count)
+}
+
+func ExampleClient_ReconcileTuples() {
+	// Make the tuples in the store match the declared ones
+	desired := []ofga.Tuple{{
+		Object:   &ofga.Entity{Kind: "user", ID: "alice"},
+		Relation: "editor",
+		Target:   &ofga.Entity{Kind: "document", ID: "123"},
+	}, {
+		Object:   &ofga.Entity{Kind: "team", ID: "eng", Relation: "member"},
+		Relation: "viewer",
+		Target:   &ofga.Entity{Kind: "document", ID: "123"},
+	}}
+	added, removed, err := client.ReconcileTuples(context.Background(), desired)
+	if err != nil {
+		// Handle error
+	}
+	fmt.Println(added, removed)
+}
+
+func ExampleChangeReader_Read() {
+	// Use a persistent TokenStore implementation (for instance backed by a
+	// file or a database) to resume reading changes across restarts.
+	store := &ofga.MemoryTokenStore{}
+	reader := ofga.NewChangeReader(client, store, "document", 50)
+
+	err := reader.Read(context.Background(), func(changes []openfga.TupleChange) error {
+		for _, change := range changes {
+			fmt.Println(change.Operation, change.TupleKey.Object)
+		}
+		return nil
+	})
+	if err != nil {
+		// Handle error
+	}
+}
+
+func ExampleEncodeID() {
+	// Encode an ID containing characters that are not allowed in entity IDs.
+	entity, err := ofga.NewEntity("document", ofga.EncodeID("urn:isbn:0451450523"))
+	if err != nil {
+		// Handle error
+	}
+	fmt.Println(entity.String())
+
+	// Decode the ID of a parsed entity.
+	parsed, err := ofga.ParseEntity(entity.String())
+	if err != nil {
+		// Handle error
+	}
+	id, err := ofga.DecodeID(parsed.ID)
+	if err != nil {
+		// Handle error
+	}
+	fmt.Println(id)
+	// Output:
+	// document:urn%3Aisbn%3A0451450523
+	// urn:isbn:0451450523
+}
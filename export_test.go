@@ -3,8 +3,9 @@
 
 package ofga
 
+import "time"
+
 var (
-	TuplesToOpenFGATupleKeys                        = tuplesToOpenFGATupleKeys
 	TupleIsEmpty                                    = (*Tuple).isEmpty
 	ValidateTupleForFindMatchingTuples              = validateTupleForFindMatchingTuples
 	ValidateTupleForFindUsersByRelation             = validateTupleForFindUsersByRelation
@@ -13,4 +14,17 @@ var (
 	Expand                                          = (*Client).expand
 	ExpandComputed                                  = (*Client).expandComputed
 	ValidateTupleForFindAccessibleObjectsByRelation = validateTupleForFindAccessibleObjectsByRelation
+	NewHTTPClient                                   = newHTTPClient
 )
+
+// SetCachingClientNow replaces the function used by the CachingClient to
+// determine the current time.
+func SetCachingClientNow(c *CachingClient, now func() time.Time) {
+	c.now = now
+}
+
+// SetCircuitBreakerNow replaces the function used by the circuit breaker of
+// the given client to determine the current time.
+func SetCircuitBreakerNow(c *Client, now func() time.Time) {
+	c.breaker.now = now
+}
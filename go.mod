@@ -8,6 +8,7 @@ require (
 	github.com/juju/zaputil v0.0.0-20190326175239-ef53049637ac
 	github.com/openfga/go-sdk v0.6.3
 	go.uber.org/zap v1.27.0
+	golang.org/x/net v0.35.0
 )
 
 require (
@@ -21,4 +22,5 @@ require (
 	go.opentelemetry.io/otel/metric v1.32.0 // indirect
 	go.opentelemetry.io/otel/trace v1.32.0 // indirect
 	go.uber.org/multierr v1.11.0 // indirect
+	golang.org/x/text v0.22.0 // indirect
 )
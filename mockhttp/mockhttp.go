@@ -7,13 +7,19 @@
 package mockhttp
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 
 	qt "github.com/frankban/quicktest"
 	"github.com/jarcoal/httpmock"
+	openfga "github.com/openfga/go-sdk"
+
+	"github.com/canonical/ofga"
 )
 
 // Route represents a callable API endpoint.
@@ -40,6 +46,20 @@ type RouteResponder struct {
 	// ExpectedReqBody allows to specify the expected request body for requests
 	// that call this Route.
 	ExpectedReqBody any
+	// ExactJSONMatch causes the request body to be compared with the JSON
+	// encoding of ExpectedReqBody as is, rather than after decoding both into
+	// maps. Object keys are still allowed to appear in any order, but numbers
+	// must be encoded in the same way (e.g. 10 does not match 10.0). A
+	// json.RawMessage can be used as ExpectedReqBody to specify the exact
+	// JSON expected.
+	ExactJSONMatch bool
+	// ReqBodyMatcher allows to specify a function validating the body of
+	// requests that call this Route. When set, it is used instead of
+	// comparing the body with ExpectedReqBody, so that requests including
+	// non-deterministic values (e.g. generated IDs or timestamps) can be
+	// validated by only checking the relevant fields. The request body is
+	// valid if the function returns a nil error.
+	ReqBodyMatcher func(body []byte) error
 	// ExpectedReqQueryParams allows to specify the expected request query
 	// params for requests that call this Route.
 	ExpectedReqQueryParams url.Values
@@ -86,7 +106,17 @@ func (r *RouteResponder) Generate() httpmock.Responder {
 // Finish runs validations for the route, ensuring that the received request
 // matches the predefined expectations.
 func (r *RouteResponder) Finish(c *qt.C) {
-	if r.ExpectedReqBody != nil {
+	if r.ReqBodyMatcher != nil {
+		body, err := io.ReadAll(r.req.Body)
+		c.Assert(err, qt.IsNil)
+		c.Assert(r.ReqBodyMatcher(body), qt.IsNil, qt.Commentf("request body mismatch: %s", body))
+	} else if r.ExpectedReqBody != nil && r.ExactJSONMatch {
+		body, err := io.ReadAll(r.req.Body)
+		c.Assert(err, qt.IsNil)
+		expectedBody, err := json.Marshal(r.ExpectedReqBody)
+		c.Assert(err, qt.IsNil)
+		c.Assert(normalizeJSON(c, body), qt.Equals, normalizeJSON(c, expectedBody))
+	} else if r.ExpectedReqBody != nil {
 		body := make(map[string]any)
 		err := json.NewDecoder(r.req.Body).Decode(&body)
 		c.Assert(err, qt.IsNil)
@@ -109,3 +139,70 @@ func (r *RouteResponder) Finish(c *qt.C) {
 		}
 	}
 }
+
+// normalizeJSON returns the given JSON encoded data with object keys sorted
+// and insignificant white space removed. Numbers are kept as they appear in
+// the data.
+func normalizeJSON(c *qt.C, data []byte) string {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	err := dec.Decode(&v)
+	c.Assert(err, qt.IsNil)
+	normalized, err := json.Marshal(v)
+	c.Assert(err, qt.IsNil)
+	return string(normalized)
+}
+
+var (
+	// ListStoresRoute is the route used to list the stores.
+	ListStoresRoute = Route{Method: http.MethodGet, Endpoint: "/stores"}
+	// GetStoreRoute is the route used to retrieve a store.
+	GetStoreRoute = Route{Method: http.MethodGet, Endpoint: `=~/stores/(\w+)\z`}
+	// ReadAuthModelRoute is the route used to retrieve an authorization
+	// model.
+	ReadAuthModelRoute = Route{Method: http.MethodGet, Endpoint: `=~/stores/(\w+)/authorization-models/(\w+)\z`}
+)
+
+// NewTestServer mocks the OpenFGA server and returns an ofga.Client created
+// with the given params. The routes called while creating the client
+// (ListStoresRoute, GetStoreRoute and ReadAuthModelRoute) are mocked by
+// default so that they report that the store and the authorization model in
+// params exist. The given routes are registered along with the default ones,
+// replacing the default route responders with the same route: this can be
+// used, for instance, to simulate a missing authorization model.
+//
+// The given routes are validated with Finish once the client has been
+// created. The mocked routes are only available while creating the client:
+// tests must register the responders for the routes they exercise.
+func NewTestServer(c *qt.C, params ofga.OpenFGAParams, routes ...*RouteResponder) *ofga.Client {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	defaultRoutes := []*RouteResponder{{
+		Route: ListStoresRoute,
+	}, {
+		Route: GetStoreRoute,
+		MockResponse: openfga.GetStoreResponse{
+			Id:   params.StoreID,
+			Name: "Test Store",
+		},
+	}, {
+		Route: ReadAuthModelRoute,
+		MockResponse: openfga.ReadAuthorizationModelResponse{AuthorizationModel: &openfga.AuthorizationModel{
+			Id:            params.AuthModelID,
+			SchemaVersion: "1.1",
+		}},
+	}}
+	for _, r := range append(defaultRoutes, routes...) {
+		httpmock.RegisterResponder(r.Route.Method, r.Route.Endpoint, r.Generate())
+	}
+
+	client, err := ofga.NewClient(context.Background(), params)
+	c.Assert(err, qt.IsNil)
+
+	for _, r := range routes {
+		r.Finish(c)
+	}
+	return client
+}
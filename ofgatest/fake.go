@@ -0,0 +1,972 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the LGPL license, see LICENSE file for details.
+
+// Package ofgatest provides an in-memory fake of the ofga Client, allowing
+// packages depending on ofga to unit test their authorization logic without
+// mocking HTTP requests or running an OpenFGA server.
+package ofgatest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	openfga "github.com/openfga/go-sdk"
+
+	"github.com/canonical/ofga"
+)
+
+// Fake is an in-memory implementation of the methods of ofga.Client, storing
+// relationship tuples in memory and resolving checks against an optional
+// authorization model. Code under test can depend on an interface declaring
+// the subset of the methods of ofga.Client it uses, which is satisfied by both
+// *ofga.Client and *Fake.
+//
+// Methods that require an OpenFGA server, such as the ones managing stores and
+// authorization models or reading changes, are not supported and return an
+// error wrapping ErrUnsupported.
+//
+// Only direct relations (`[user]`), computed relations (`define viewer:
+// editor`) and unions of them (`define viewer: [user] or editor`) are
+// supported: checking a relation defined with any other construct (e.g.
+// intersections, exclusions or `from` relations) returns an error. Conditions
+// are not supported either, and tuples with conditions cannot be added.
+//
+// A Fake is safe for concurrent use.
+type Fake struct {
+	model       *openfga.AuthorizationModel
+	storeID     string
+	authModelID string
+
+	store *store
+}
+
+// store holds the tuples of a Fake, shared with its clones.
+type store struct {
+	mu     sync.Mutex
+	tuples []ofga.TimestampedTuple
+}
+
+// NewFake returns a Fake resolving relations as defined by the given
+// authorization model. If the model is nil, all relations are considered to
+// be direct relations, so that checks only succeed for tuples that were
+// explicitly added.
+func NewFake(model *openfga.AuthorizationModel) *Fake {
+	return &Fake{
+		model: model,
+		store: &store{},
+	}
+}
+
+// AuthModelID returns the authorization model ID set with SetAuthModelID, as
+// done by ofga.Client.AuthModelID. The ID is not used by the Fake.
+func (f *Fake) AuthModelID() string {
+	return f.authModelID
+}
+
+// SetAuthModelID sets the authorization model ID returned by AuthModelID.
+func (f *Fake) SetAuthModelID(authModelID string) {
+	f.authModelID = authModelID
+}
+
+// StoreID returns the store ID set with SetStoreID, as done by
+// ofga.Client.StoreID. The ID is not used by the Fake.
+func (f *Fake) StoreID() string {
+	return f.storeID
+}
+
+// SetStoreID sets the store ID returned by StoreID.
+func (f *Fake) SetStoreID(storeID string) {
+	f.storeID = storeID
+}
+
+// IsConfigured reports whether a store ID and an authorization model ID are
+// set, as done by ofga.Client.IsConfigured.
+func (f *Fake) IsConfigured() (hasStore bool, hasModel bool) {
+	return f.storeID != "", f.authModelID != ofga.LatestAuthModel
+}
+
+// CircuitBreakerState always returns ofga.CircuitBreakerClosed, as the Fake
+// does not send requests.
+func (f *Fake) CircuitBreakerState() ofga.CircuitBreakerState {
+	return ofga.CircuitBreakerClosed
+}
+
+// Clone returns a new Fake sharing the tuples and the authorization model
+// with f, but whose store ID and authorization model ID can be set
+// independently, as done by ofga.Client.Clone.
+func (f *Fake) Clone() *Fake {
+	clone := *f
+	return &clone
+}
+
+// AddRelation adds the given tuples, as done by ofga.Client.AddRelation.
+func (f *Fake) AddRelation(ctx context.Context, tuples ...ofga.Tuple) error {
+	return f.AddRemoveRelations(ctx, tuples, nil)
+}
+
+// RemoveRelation removes the given tuples, as done by
+// ofga.Client.RemoveRelation.
+func (f *Fake) RemoveRelation(ctx context.Context, tuples ...ofga.Tuple) error {
+	return f.AddRemoveRelations(ctx, nil, tuples)
+}
+
+// AddRemoveRelations adds and removes the given tuples atomically, as done by
+// ofga.Client.AddRemoveRelations. Like the OpenFGA server, it fails without
+// applying any change if a tuple to be added already exists or if a tuple to
+// be removed does not exist.
+func (f *Fake) AddRemoveRelations(ctx context.Context, addTuples, removeTuples []ofga.Tuple) error {
+	f.store.mu.Lock()
+	defer f.store.mu.Unlock()
+
+	existing := make(map[string]bool, len(f.store.tuples))
+	for _, t := range f.store.tuples {
+		existing[key(t.Tuple)] = true
+	}
+	for _, t := range removeTuples {
+		if err := validateTuple(t); err != nil {
+			return fmt.Errorf("cannot add or remove relations: %v", err)
+		}
+		if !existing[key(t)] {
+			return fmt.Errorf("cannot add or remove relations: cannot delete a tuple which does not exist: %s", t)
+		}
+		delete(existing, key(t))
+	}
+	tuples := make([]ofga.TimestampedTuple, 0, len(f.store.tuples)+len(addTuples))
+	for _, t := range f.store.tuples {
+		if existing[key(t.Tuple)] {
+			tuples = append(tuples, t)
+		}
+	}
+	now := time.Now()
+	for _, t := range addTuples {
+		if err := validateTuple(t); err != nil {
+			return fmt.Errorf("cannot add or remove relations: %v", err)
+		}
+		if t.Condition != nil {
+			return fmt.Errorf("cannot add or remove relations: invalid tuple %s: conditions are not supported", t)
+		}
+		if existing[key(t)] {
+			return fmt.Errorf("cannot add or remove relations: cannot write a tuple which already exists: %s", t)
+		}
+		existing[key(t)] = true
+		tuples = append(tuples, ofga.TimestampedTuple{
+			Tuple:        copyTuple(t),
+			Timestamp:    now,
+			HasTimestamp: true,
+		})
+	}
+	f.store.tuples = tuples
+	return nil
+}
+
+// AddRelationSafe adds the given tuples, ignoring the ones that already
+// exist, as done by ofga.Client.AddRelationSafe.
+func (f *Fake) AddRelationSafe(ctx context.Context, tuples ...ofga.Tuple) error {
+	_, err := f.addMissingRelations(ctx, tuples)
+	return err
+}
+
+// AddRelationIfAbsent adds the given tuple if it does not exist, reporting
+// whether it was added, as done by ofga.Client.AddRelationIfAbsent.
+func (f *Fake) AddRelationIfAbsent(ctx context.Context, tuple ofga.Tuple) (created bool, err error) {
+	n, err := f.addMissingRelations(ctx, []ofga.Tuple{tuple})
+	return n == 1, err
+}
+
+// SetRelation adds or removes the given tuple depending on grant, tolerating
+// tuples that already exist or do not exist, as done by
+// ofga.Client.SetRelation.
+func (f *Fake) SetRelation(ctx context.Context, tuple ofga.Tuple, grant bool) error {
+	if grant {
+		return f.AddRelationSafe(ctx, tuple)
+	}
+	_, err := f.removeExistingRelations(ctx, []ofga.Tuple{tuple})
+	return err
+}
+
+// RemoveRelationBatched removes the given tuples, ignoring the ones that do
+// not exist, and returns the number of removed tuples, as done by
+// ofga.Client.RemoveRelationBatched. As the Fake does not send requests, the
+// tuples are removed at once regardless of batchSize.
+func (f *Fake) RemoveRelationBatched(ctx context.Context, tuples []ofga.Tuple, batchSize int) (int, error) {
+	return f.removeExistingRelations(ctx, tuples)
+}
+
+// ReconcileTuples adds and removes tuples so that the stored tuples match the
+// desired ones, as done by ofga.Client.ReconcileTuples, returning the numbers
+// of added and removed tuples.
+func (f *Fake) ReconcileTuples(ctx context.Context, desired []ofga.Tuple) (added, removed int, err error) {
+	desiredKeys := make(map[string]bool, len(desired))
+	for _, t := range desired {
+		if err := validateTuple(t); err != nil {
+			return 0, 0, fmt.Errorf("invalid desired tuple: %v", err)
+		}
+		desiredKeys[key(t)] = true
+	}
+	stored, _, err := f.FindMatchingTuples(ctx, ofga.Tuple{}, 0, "")
+	if err != nil {
+		return 0, 0, err
+	}
+	storedKeys := make(map[string]bool, len(stored))
+	var toRemove []ofga.Tuple
+	for _, t := range stored {
+		storedKeys[key(t.Tuple)] = true
+		if !desiredKeys[key(t.Tuple)] {
+			toRemove = append(toRemove, t.Tuple)
+		}
+	}
+	var toAdd []ofga.Tuple
+	for _, t := range desired {
+		if !storedKeys[key(t)] {
+			toAdd = append(toAdd, t)
+			storedKeys[key(t)] = true
+		}
+	}
+	if err := f.AddRemoveRelations(ctx, toAdd, toRemove); err != nil {
+		return 0, 0, err
+	}
+	return len(toAdd), len(toRemove), nil
+}
+
+// exportedTuple is the JSON representation of a relationship tuple used by
+// ExportTuples and ImportTuples, as done by ofga.Client.ExportTuples.
+type exportedTuple struct {
+	Object    string          `json:"object"`
+	Relation  string          `json:"relation"`
+	Target    string          `json:"target"`
+	Condition json.RawMessage `json:"condition,omitempty"`
+}
+
+// ExportTuples writes all the stored tuples to w as newline-delimited JSON
+// objects, in the format used by ofga.Client.ExportTuples, and returns the
+// number of written tuples.
+func (f *Fake) ExportTuples(ctx context.Context, w io.Writer) (int, error) {
+	return f.ExportTuplesWithOptions(ctx, w, ofga.ExportTuplesOptions{})
+}
+
+// ExportTuplesWithOptions behaves like ExportTuples, only exporting the tuples
+// whose target is of one of the kinds specified in opts, if any, as done by
+// ofga.Client.ExportTuplesWithOptions.
+func (f *Fake) ExportTuplesWithOptions(ctx context.Context, w io.Writer, opts ofga.ExportTuplesOptions) (int, error) {
+	tuples, _, err := f.FindMatchingTuples(ctx, ofga.Tuple{}, 0, "")
+	if err != nil {
+		return 0, err
+	}
+	enc := json.NewEncoder(w)
+	count := 0
+	for _, t := range tuples {
+		if len(opts.Kinds) > 0 && !slices.Contains(opts.Kinds, t.Tuple.Target.Kind) {
+			continue
+		}
+		if err := enc.Encode(exportedTuple{
+			Object:   t.Tuple.Object.String(),
+			Relation: t.Tuple.Relation.String(),
+			Target:   t.Tuple.Target.String(),
+		}); err != nil {
+			return count, fmt.Errorf("cannot write tuple: %v", err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// ImportTuples reads tuples from r, in the format produced by ExportTuples,
+// and adds them, skipping the ones that already exist, as done by
+// ofga.Client.ImportTuples. As the Fake does not send requests, batchSize is
+// only validated. The number of imported tuples (including any that were
+// already present) is returned. Tuples with conditions cannot be imported.
+func (f *Fake) ImportTuples(ctx context.Context, r io.Reader, batchSize int) (int, error) {
+	if batchSize < 1 {
+		return 0, errors.New("batchSize must be greater than or equal to 1")
+	}
+	scanner := bufio.NewScanner(r)
+	var tuples []ofga.Tuple
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		var et exportedTuple
+		if err := json.Unmarshal(data, &et); err != nil {
+			return 0, fmt.Errorf("cannot parse tuple on line %d: %v", line, err)
+		}
+		if et.Condition != nil {
+			return 0, fmt.Errorf("invalid tuple on line %d: conditions are not supported", line)
+		}
+		t, err := ofga.ParseTuple(et.Object, et.Relation, et.Target)
+		if err == nil {
+			err = t.Validate()
+		}
+		if err != nil {
+			return 0, fmt.Errorf("invalid tuple on line %d: %v", line, err)
+		}
+		tuples = append(tuples, t)
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("cannot read tuples after line %d: %v", line, err)
+	}
+	if err := f.AddRelationSafe(ctx, tuples...); err != nil {
+		return 0, err
+	}
+	return len(tuples), nil
+}
+
+// addMissingRelations adds the given tuples, ignoring the ones that already
+// exist, and returns the number of added tuples.
+func (f *Fake) addMissingRelations(ctx context.Context, tuples []ofga.Tuple) (int, error) {
+	var missing []ofga.Tuple
+	for _, t := range tuples {
+		if !f.exists(t) {
+			missing = append(missing, t)
+		}
+	}
+	if err := f.AddRelation(ctx, missing...); err != nil {
+		return 0, err
+	}
+	return len(missing), nil
+}
+
+// removeExistingRelations removes the given tuples, ignoring the ones that do
+// not exist, and returns the number of removed tuples.
+func (f *Fake) removeExistingRelations(ctx context.Context, tuples []ofga.Tuple) (int, error) {
+	var existing []ofga.Tuple
+	for _, t := range tuples {
+		if f.exists(t) {
+			existing = append(existing, t)
+		}
+	}
+	if err := f.RemoveRelation(ctx, existing...); err != nil {
+		return 0, err
+	}
+	return len(existing), nil
+}
+
+// exists reports whether the given tuple is stored.
+func (f *Fake) exists(t ofga.Tuple) bool {
+	f.store.mu.Lock()
+	defer f.store.mu.Unlock()
+
+	for _, stored := range f.store.tuples {
+		if key(stored.Tuple) == key(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckRelation checks whether the relation specified by the given tuple
+// holds, as done by ofga.Client.CheckRelation. The contextual tuples are
+// considered along with the stored ones for this check only.
+func (f *Fake) CheckRelation(ctx context.Context, tuple ofga.Tuple, contextualTuples ...ofga.Tuple) (bool, error) {
+	if err := validateTuple(tuple); err != nil {
+		return false, fmt.Errorf("cannot check relation: %v", err)
+	}
+	r := f.newResolver(contextualTuples)
+	allowed, err := r.check(*tuple.Object, tuple.Relation, *tuple.Target)
+	if err != nil {
+		return false, fmt.Errorf("cannot check relation: %v", err)
+	}
+	return allowed, nil
+}
+
+// CheckRelationWithOptions behaves like CheckRelation, as done by
+// ofga.Client.CheckRelationWithOptions. As conditions are not supported, the
+// context in opts is ignored.
+func (f *Fake) CheckRelationWithOptions(ctx context.Context, tuple ofga.Tuple, opts ofga.CheckRelationOptions) (bool, error) {
+	return f.CheckRelation(ctx, tuple, opts.ContextualTuples...)
+}
+
+// CheckRelationWithTracing behaves like CheckRelation.
+func (f *Fake) CheckRelationWithTracing(ctx context.Context, tuple ofga.Tuple, contextualTuples ...ofga.Tuple) (bool, error) {
+	return f.CheckRelation(ctx, tuple, contextualTuples...)
+}
+
+// CheckRelationTrace behaves like CheckRelation, and always returns an empty
+// trace.
+func (f *Fake) CheckRelationTrace(ctx context.Context, tuple ofga.Tuple, contextualTuples ...ofga.Tuple) (bool, string, error) {
+	allowed, err := f.CheckRelation(ctx, tuple, contextualTuples...)
+	return allowed, "", err
+}
+
+// CheckRelations checks which of the given relations hold between the object
+// and the target, as done by ofga.Client.CheckRelations.
+func (f *Fake) CheckRelations(ctx context.Context, object ofga.Entity, relations []ofga.Relation, target ofga.Entity, contextualTuples ...ofga.Tuple) (map[ofga.Relation]bool, error) {
+	allowed := make(map[ofga.Relation]bool, len(relations))
+	var errs []error
+	for _, relation := range relations {
+		ok, err := f.CheckRelation(ctx, ofga.Tuple{Object: &object, Relation: relation, Target: &target}, contextualTuples...)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		allowed[relation] = ok
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return allowed, nil
+}
+
+// waitForRelationInterval is the interval between checks in WaitForRelation.
+const waitForRelationInterval = 10 * time.Millisecond
+
+// WaitForRelation waits until checking the relation specified by the given
+// tuple returns the expected result, as done by ofga.Client.WaitForRelation.
+// As the Fake is always consistent, this is only useful when the tuples are
+// modified concurrently.
+func (f *Fake) WaitForRelation(ctx context.Context, tuple ofga.Tuple, expected bool, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		allowed, err := f.CheckRelation(ctx, tuple)
+		if err != nil {
+			return err
+		}
+		if allowed == expected {
+			return nil
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("timed out waiting for relation %s to be %v", tuple, expected)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(min(waitForRelationInterval, remaining)):
+		}
+	}
+}
+
+// FindMatchingTuples returns the stored tuples matching the given tuple, as
+// done by ofga.Client.FindMatchingTuples. Each of the Object, Relation and
+// Target fields of the given tuple, when set, is used as a filter. The Target
+// ID can be left empty to match all the targets of a kind. Results are not
+// paginated: the page size and continuation token are ignored, and all the
+// matching tuples are returned along with an empty continuation token.
+func (f *Fake) FindMatchingTuples(ctx context.Context, tuple ofga.Tuple, pageSize int32, continuationToken string) ([]ofga.TimestampedTuple, string, error) {
+	f.store.mu.Lock()
+	defer f.store.mu.Unlock()
+
+	var tuples []ofga.TimestampedTuple
+	for _, t := range f.store.tuples {
+		if tuple.Object != nil && *tuple.Object != *t.Tuple.Object {
+			continue
+		}
+		if tuple.Relation != "" && tuple.Relation != t.Tuple.Relation {
+			continue
+		}
+		if tuple.Target != nil {
+			if tuple.Target.Kind != t.Tuple.Target.Kind || tuple.Target.ID != "" && tuple.Target.ID != t.Tuple.Target.ID {
+				continue
+			}
+		}
+		t.Tuple = copyTuple(t.Tuple)
+		tuples = append(tuples, t)
+	}
+	return tuples, "", nil
+}
+
+// FindMatchingTuplesAnyRelation returns the stored tuples between the given
+// object and target whose relation is any of the given relations, as done by
+// ofga.Client.FindMatchingTuplesAnyRelation.
+func (f *Fake) FindMatchingTuplesAnyRelation(ctx context.Context, object, target ofga.Entity, relations []ofga.Relation, pageSize int32) ([]ofga.TimestampedTuple, error) {
+	tuples := []ofga.TimestampedTuple{}
+	for _, relation := range relations {
+		query := ofga.Tuple{Relation: relation, Target: &target}
+		if object != (ofga.Entity{}) {
+			query.Object = &object
+		}
+		found, _, err := f.FindMatchingTuples(ctx, query, pageSize, "")
+		if err != nil {
+			return nil, err
+		}
+		tuples = append(tuples, found...)
+	}
+	return tuples, nil
+}
+
+// FindTuplesReferencing returns the stored tuples in which the given entity
+// appears as the object or as the target, as done by
+// ofga.Client.FindTuplesReferencing.
+func (f *Fake) FindTuplesReferencing(ctx context.Context, entity ofga.Entity) ([]ofga.TimestampedTuple, error) {
+	if entity.Kind == "" || entity.ID == "" || entity.Relation != "" {
+		return nil, errors.New("invalid entity for FindTuplesReferencing: only entity.Kind and entity.ID must be set")
+	}
+	f.store.mu.Lock()
+	defer f.store.mu.Unlock()
+
+	tuples := []ofga.TimestampedTuple{}
+	for _, t := range f.store.tuples {
+		if *t.Tuple.Object == entity || *t.Tuple.Target == entity {
+			t.Tuple = copyTuple(t.Tuple)
+			tuples = append(tuples, t)
+		}
+	}
+	return tuples, nil
+}
+
+// FindAccessibleObjectsByRelation returns the objects of the kind specified
+// by tuple.Target that tuple.Object has the given relation with, as done by
+// ofga.Client.FindAccessibleObjectsByRelation. The contextual tuples are
+// considered along with the stored ones for this query only. The objects are
+// returned sorted by ID.
+func (f *Fake) FindAccessibleObjectsByRelation(ctx context.Context, tuple ofga.Tuple, contextualTuples ...ofga.Tuple) ([]ofga.Entity, error) {
+	if tuple.Object == nil || tuple.Object.Kind == "" || tuple.Object.ID == "" {
+		return nil, errors.New("invalid tuple for FindAccessibleObjectsByRelation: missing tuple.Object")
+	}
+	if tuple.Relation == "" {
+		return nil, errors.New("invalid tuple for FindAccessibleObjectsByRelation: missing tuple.Relation")
+	}
+	if tuple.Target == nil || tuple.Target.Kind == "" || tuple.Target.ID != "" || tuple.Target.Relation != "" {
+		return nil, errors.New("invalid tuple for FindAccessibleObjectsByRelation: only tuple.Target.Kind must be set")
+	}
+
+	r := f.newResolver(contextualTuples)
+	// Objects can only be reached through tuples, so the candidates are the
+	// targets of the given kind.
+	seen := make(map[ofga.Entity]bool)
+	var objects []ofga.Entity
+	for _, t := range r.tuples {
+		target := *t.Target
+		if target.Kind != tuple.Target.Kind || seen[target] {
+			continue
+		}
+		seen[target] = true
+		allowed, err := r.check(*tuple.Object, tuple.Relation, target)
+		if err != nil {
+			return nil, fmt.Errorf("cannot list objects: %v", err)
+		}
+		if allowed {
+			objects = append(objects, target)
+		}
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].ID < objects[j].ID
+	})
+	return objects, nil
+}
+
+// FindAccessibleObjectsByRelationWithLimit behaves like
+// FindAccessibleObjectsByRelation, but returns at most limit objects, as done
+// by ofga.Client.FindAccessibleObjectsByRelationWithLimit.
+func (f *Fake) FindAccessibleObjectsByRelationWithLimit(ctx context.Context, tuple ofga.Tuple, limit int, contextualTuples ...ofga.Tuple) ([]ofga.Entity, error) {
+	if limit < 1 {
+		return nil, errors.New(`limit must be greater than or equal to 1`)
+	}
+	return f.FindAccessibleObjectsByRelationWithOptions(ctx, tuple, ofga.FindAccessibleObjectsOptions{
+		ContextualTuples: contextualTuples,
+		Limit:            limit,
+	})
+}
+
+// FindAccessibleObjectsByRelationWithOptions behaves like
+// FindAccessibleObjectsByRelation, applying the limit specified in opts, as
+// done by ofga.Client.FindAccessibleObjectsByRelationWithOptions. As the
+// objects are always sorted, and conditions are not supported, the Sorted and
+// Context options are ignored.
+func (f *Fake) FindAccessibleObjectsByRelationWithOptions(ctx context.Context, tuple ofga.Tuple, opts ofga.FindAccessibleObjectsOptions) ([]ofga.Entity, error) {
+	if opts.Limit < 0 {
+		return nil, errors.New(`limit must not be negative`)
+	}
+	objects, err := f.FindAccessibleObjectsByRelation(ctx, tuple, opts.ContextualTuples...)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Limit > 0 && len(objects) > opts.Limit {
+		objects = objects[:opts.Limit]
+	}
+	return objects, nil
+}
+
+// FindAccessibleObjectIDsByRelation behaves like
+// FindAccessibleObjectsByRelation, but returns the string representations of
+// the objects, as done by ofga.Client.FindAccessibleObjectIDsByRelation.
+func (f *Fake) FindAccessibleObjectIDsByRelation(ctx context.Context, tuple ofga.Tuple, contextualTuples ...ofga.Tuple) ([]string, error) {
+	objects, err := f.FindAccessibleObjectsByRelation(ctx, tuple, contextualTuples...)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(objects))
+	for i, o := range objects {
+		ids[i] = o.String()
+	}
+	return ids, nil
+}
+
+// FindUserGrants returns, for each relation defined on targetKind in the
+// authorization model, the objects of that kind the user has the relation
+// with, as done by ofga.Client.FindUserGrants. It requires the Fake to be
+// created with an authorization model.
+func (f *Fake) FindUserGrants(ctx context.Context, user ofga.Entity, targetKind ofga.Kind) (map[ofga.Relation][]ofga.Entity, error) {
+	if user.Kind == "" || user.ID == "" || user.Relation != "" {
+		return nil, errors.New("invalid user for FindUserGrants: only user.Kind and user.ID must be set")
+	}
+	model, err := f.GetAuthModel(ctx, f.authModelID)
+	if err != nil {
+		return nil, err
+	}
+	var relations []string
+	found := false
+	for _, td := range model.TypeDefinitions {
+		if td.Type == targetKind.String() {
+			found = true
+			for r := range td.GetRelations() {
+				relations = append(relations, r)
+			}
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("type %q not found in the authorization model", targetKind)
+	}
+	sort.Strings(relations)
+
+	grants := make(map[ofga.Relation][]ofga.Entity)
+	for _, r := range relations {
+		objects, err := f.FindAccessibleObjectsByRelation(ctx, ofga.Tuple{
+			Object:   &user,
+			Relation: ofga.Relation(r),
+			Target:   &ofga.Entity{Kind: targetKind},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(objects) > 0 {
+			grants[ofga.Relation(r)] = objects
+		}
+	}
+	return grants, nil
+}
+
+// FindUsersByRelation returns the users that have the given relation with the
+// target, as done by ofga.Client.FindUsersByRelation. The returned users are
+// the individual users and public access entities (`user:*`) found in the
+// tuples, sorted by their string representation. As the Fake resolves all
+// relations, usersets are always fully expanded, and maxDepth is only
+// validated.
+func (f *Fake) FindUsersByRelation(ctx context.Context, tuple ofga.Tuple, maxDepth int) ([]ofga.Entity, error) {
+	if maxDepth < 1 {
+		return nil, errors.New(`maxDepth must be greater than or equal to 1`)
+	}
+	if tuple.Target == nil || tuple.Target.Kind == "" || tuple.Target.ID == "" || tuple.Target.Relation != "" {
+		return nil, errors.New("invalid tuple for FindUsersByRelation: only tuple.Target.Kind and tuple.Target.ID must be set")
+	}
+	if tuple.Relation == "" {
+		return nil, errors.New("invalid tuple for FindUsersByRelation: missing tuple.Relation")
+	}
+	r := f.newResolver(nil)
+	users, err := r.users(tuple.Relation, *tuple.Target, func(e ofga.Entity) bool {
+		return e.Relation == ""
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot find users: %v", err)
+	}
+	return users, nil
+}
+
+// ListUsers returns the users of the kind, and optionally relation, specified
+// by tuple.Object that have the given relation with the target, as done by
+// ofga.Client.ListUsers. The contextual tuples specified in opts are
+// considered along with the stored ones for this query only, while the
+// context is ignored, as conditions are not supported. The users are returned
+// sorted by their string representation.
+func (f *Fake) ListUsers(ctx context.Context, tuple ofga.Tuple, opts ofga.ListUsersOptions) ([]ofga.Entity, error) {
+	if tuple.Object == nil || tuple.Object.Kind == "" || tuple.Object.ID != "" {
+		return nil, errors.New("invalid tuple for ListUsers: only tuple.Object.Kind and optionally tuple.Object.Relation must be set")
+	}
+	if tuple.Relation == "" {
+		return nil, errors.New("invalid tuple for ListUsers: missing tuple.Relation")
+	}
+	if tuple.Target == nil || tuple.Target.Kind == "" || tuple.Target.ID == "" || tuple.Target.Relation != "" {
+		return nil, errors.New("invalid tuple for ListUsers: only tuple.Target.Kind and tuple.Target.ID must be set")
+	}
+	filter := *tuple.Object
+	if opts.DirectOnly {
+		if len(opts.ContextualTuples) > 0 || opts.Context != nil {
+			return nil, errors.New("contextual tuples and context cannot be used to list direct users")
+		}
+		tuples, _, err := f.FindMatchingTuples(ctx, ofga.Tuple{Relation: tuple.Relation, Target: tuple.Target}, 0, "")
+		if err != nil {
+			return nil, err
+		}
+		users := []ofga.Entity{}
+		for _, t := range tuples {
+			if t.Tuple.Object.Kind == filter.Kind && t.Tuple.Object.Relation == filter.Relation {
+				users = append(users, *t.Tuple.Object)
+			}
+		}
+		return users, nil
+	}
+	r := f.newResolver(opts.ContextualTuples)
+	users, err := r.users(tuple.Relation, *tuple.Target, func(e ofga.Entity) bool {
+		return e.Kind == filter.Kind && e.Relation == filter.Relation
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot list users: %v", err)
+	}
+	return users, nil
+}
+
+// GetAuthModel returns the authorization model of the Fake, regardless of the
+// given ID, or an error if the Fake was created without a model.
+func (f *Fake) GetAuthModel(ctx context.Context, ID string) (openfga.AuthorizationModel, error) {
+	if f.model == nil {
+		return openfga.AuthorizationModel{}, errors.New("no authorization model found")
+	}
+	return *f.model, nil
+}
+
+// GetAuthModelAsDSL returns the authorization model of the Fake rendered using
+// the OpenFGA modeling language, as done by ofga.Client.GetAuthModelAsDSL.
+func (f *Fake) GetAuthModelAsDSL(ctx context.Context, id string) (string, error) {
+	model, err := f.GetAuthModel(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	dsl, err := ofga.AuthModelToDSL(model)
+	if err != nil {
+		return "", fmt.Errorf("cannot render authorization model %s: %v", id, err)
+	}
+	return dsl, nil
+}
+
+// ListAuthModels returns the authorization model of the Fake, if any, in a
+// single page.
+func (f *Fake) ListAuthModels(ctx context.Context, pageSize int32, continuationToken string) (openfga.ReadAuthorizationModelsResponse, error) {
+	models, err := f.ListAllAuthModels(ctx)
+	if err != nil {
+		return openfga.ReadAuthorizationModelsResponse{}, err
+	}
+	return openfga.ReadAuthorizationModelsResponse{AuthorizationModels: models}, nil
+}
+
+// ListAllAuthModels returns the authorization model of the Fake, if any.
+func (f *Fake) ListAllAuthModels(ctx context.Context) ([]openfga.AuthorizationModel, error) {
+	models := []openfga.AuthorizationModel{}
+	if f.model != nil {
+		models = append(models, *f.model)
+	}
+	return models, nil
+}
+
+// FindAuthModelsBySchemaVersion returns the authorization model of the Fake
+// if it uses the given schema version.
+func (f *Fake) FindAuthModelsBySchemaVersion(ctx context.Context, schemaVersion string) ([]openfga.AuthorizationModel, error) {
+	models := []openfga.AuthorizationModel{}
+	if f.model != nil && f.model.SchemaVersion == schemaVersion {
+		models = append(models, *f.model)
+	}
+	return models, nil
+}
+
+// AssertModelHasRelations verifies that the authorization model of the Fake
+// defines all the given types and relations, as done by
+// ofga.Client.AssertModelHasRelations.
+func (f *Fake) AssertModelHasRelations(ctx context.Context, required map[ofga.Kind][]ofga.Relation) error {
+	model, err := f.GetAuthModel(ctx, f.authModelID)
+	if err != nil {
+		return err
+	}
+	relations := make(map[ofga.Kind]map[string]openfga.Userset, len(model.TypeDefinitions))
+	for _, td := range model.TypeDefinitions {
+		relations[ofga.Kind(td.Type)] = td.GetRelations()
+	}
+	var missing []string
+	for kind, rels := range required {
+		defined, ok := relations[kind]
+		if !ok {
+			missing = append(missing, "type "+kind.String())
+			continue
+		}
+		for _, rel := range rels {
+			if _, ok := defined[rel.String()]; !ok {
+				missing = append(missing, "relation "+kind.String()+"#"+rel.String())
+			}
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("authorization model %s is missing %s", model.GetId(), strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// newResolver returns a resolver for the stored tuples along with the given
+// contextual tuples.
+func (f *Fake) newResolver(contextualTuples []ofga.Tuple) *resolver {
+	f.store.mu.Lock()
+	defer f.store.mu.Unlock()
+
+	tuples := make([]ofga.Tuple, 0, len(f.store.tuples)+len(contextualTuples))
+	for _, t := range f.store.tuples {
+		tuples = append(tuples, t.Tuple)
+	}
+	for _, t := range contextualTuples {
+		if validateTuple(t) == nil {
+			tuples = append(tuples, t)
+		}
+	}
+	return &resolver{
+		model:   f.model,
+		tuples:  tuples,
+		visited: make(map[string]bool),
+	}
+}
+
+// resolver resolves relations for a snapshot of tuples.
+type resolver struct {
+	model  *openfga.AuthorizationModel
+	tuples []ofga.Tuple
+	// visited holds the checks in progress, so that cycles in the
+	// relationship graph (e.g. groups being members of each other) do not
+	// cause infinite recursion.
+	visited map[string]bool
+	// ignorePublicAccess causes relations granted through public access
+	// entities (`user:*`) not to be considered, so that listed users having
+	// a relation only by virtue of public access are not returned.
+	ignorePublicAccess bool
+}
+
+// check reports whether the object has the given relation with the target.
+func (r *resolver) check(object ofga.Entity, relation ofga.Relation, target ofga.Entity) (bool, error) {
+	k := object.String() + "#" + relation.String() + "@" + target.String()
+	if r.visited[k] {
+		return false, nil
+	}
+	r.visited[k] = true
+	defer delete(r.visited, k)
+
+	if r.model == nil {
+		return r.checkDirect(object, relation, target)
+	}
+	userset, err := r.userset(target.Kind, relation)
+	if err != nil {
+		return false, err
+	}
+	return r.checkUserset(object, relation, target, userset)
+}
+
+// users returns the objects of the tuples selected by the given function that
+// have the given relation with the target, sorted by their string
+// representation.
+func (r *resolver) users(relation ofga.Relation, target ofga.Entity, selected func(ofga.Entity) bool) ([]ofga.Entity, error) {
+	seen := make(map[ofga.Entity]bool)
+	users := []ofga.Entity{}
+	for _, t := range r.tuples {
+		user := *t.Object
+		if seen[user] || !selected(user) {
+			continue
+		}
+		seen[user] = true
+		r.ignorePublicAccess = user.ID != "*"
+		allowed, err := r.check(user, relation, target)
+		if err != nil {
+			return nil, err
+		}
+		if allowed {
+			users = append(users, user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].String() < users[j].String()
+	})
+	return users, nil
+}
+
+// checkUserset reports whether the object has the given relation, defined by
+// the given userset, with the target.
+func (r *resolver) checkUserset(object ofga.Entity, relation ofga.Relation, target ofga.Entity, userset openfga.Userset) (bool, error) {
+	switch {
+	case userset.This != nil:
+		return r.checkDirect(object, relation, target)
+	case userset.ComputedUserset != nil:
+		return r.check(object, ofga.Relation(userset.ComputedUserset.GetRelation()), target)
+	case userset.Union != nil:
+		for _, child := range userset.Union.Child {
+			allowed, err := r.checkUserset(object, relation, target, child)
+			if err != nil || allowed {
+				return allowed, err
+			}
+		}
+		return false, nil
+	}
+	return false, fmt.Errorf("unsupported definition for relation %s#%s: only direct, computed and union relations are supported", target.Kind, relation)
+}
+
+// checkDirect reports whether the object has the given relation with the
+// target through a tuple, either directly, as a public access entity, or as
+// part of a userset.
+func (r *resolver) checkDirect(object ofga.Entity, relation ofga.Relation, target ofga.Entity) (bool, error) {
+	for _, t := range r.tuples {
+		if t.Relation != relation || *t.Target != target {
+			continue
+		}
+		switch {
+		case *t.Object == object:
+			return true, nil
+		case t.Object.ID == "*" && t.Object.Kind == object.Kind && object.Relation == "" && !r.ignorePublicAccess:
+			return true, nil
+		case t.Object.Relation != "":
+			allowed, err := r.check(object, t.Object.Relation, ofga.Entity{Kind: t.Object.Kind, ID: t.Object.ID})
+			if err != nil || allowed {
+				return allowed, err
+			}
+		}
+	}
+	return false, nil
+}
+
+// userset returns the definition of the given relation in the authorization
+// model.
+func (r *resolver) userset(kind ofga.Kind, relation ofga.Relation) (openfga.Userset, error) {
+	for _, td := range r.model.TypeDefinitions {
+		if td.Type != kind.String() {
+			continue
+		}
+		userset, ok := td.GetRelations()[relation.String()]
+		if !ok {
+			return openfga.Userset{}, fmt.Errorf("relation %s#%s not found in the authorization model", kind, relation)
+		}
+		return userset, nil
+	}
+	return openfga.Userset{}, fmt.Errorf("type %q not found in the authorization model", kind)
+}
+
+// validateTuple checks that the object, relation and target of the given
+// tuple are specified.
+func validateTuple(t ofga.Tuple) error {
+	if t.Object == nil || t.Object.Kind == "" || t.Object.ID == "" {
+		return fmt.Errorf("invalid tuple %s: missing tuple.Object", t)
+	}
+	if t.Relation == "" {
+		return fmt.Errorf("invalid tuple %s: missing tuple.Relation", t)
+	}
+	if t.Target == nil || t.Target.Kind == "" || t.Target.ID == "" {
+		return fmt.Errorf("invalid tuple %s: missing tuple.Target", t)
+	}
+	return nil
+}
+
+// key returns the key identifying a tuple, which does not include its
+// condition.
+func key(t ofga.Tuple) string {
+	return ofga.Tuple{Object: t.Object, Relation: t.Relation, Target: t.Target}.String()
+}
+
+// copyTuple returns a copy of the given tuple that does not share the
+// entities with the original one, so that stored tuples cannot be modified by
+// callers.
+func copyTuple(t ofga.Tuple) ofga.Tuple {
+	object, target := *t.Object, *t.Target
+	return ofga.Tuple{Object: &object, Relation: t.Relation, Target: &target}
+}
@@ -0,0 +1,500 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the LGPL license, see LICENSE file for details.
+
+package ofgatest_test
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/canonical/ofga"
+	"github.com/canonical/ofga/ofgatest"
+)
+
+const authModelDSL = `model
+  schema 1.1
+
+type user
+
+type group
+  relations
+    define member: [user, group#member]
+
+type document
+  relations
+    define editor: [user, group#member]
+    define viewer: [user, user:*] or editor
+    define auditor: viewer and editor
+`
+
+func tuple(object, relation, target string) ofga.Tuple {
+	t, err := ofga.ParseTuple(object, relation, target)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// query returns a tuple targeting all the objects of the given kind.
+func query(object, relation string, kind ofga.Kind) ofga.Tuple {
+	t := ofga.Tuple{
+		Relation: ofga.Relation(relation),
+		Target:   &ofga.Entity{Kind: kind},
+	}
+	if object != "" {
+		o, err := ofga.ParseEntity(object)
+		if err != nil {
+			panic(err)
+		}
+		t.Object = &o
+	}
+	return t
+}
+
+func newFake(c *qt.C) *ofgatest.Fake {
+	model, err := ofga.AuthModelFromDSL([]byte(authModelDSL))
+	c.Assert(err, qt.IsNil)
+	fake := ofgatest.NewFake(model)
+	err = fake.AddRelation(context.Background(),
+		tuple("user:alice", "member", "group:eng"),
+		tuple("group:eng#member", "member", "group:staff"),
+		tuple("group:staff#member", "editor", "document:plan"),
+		tuple("user:bob", "viewer", "document:notes"),
+		tuple("user:*", "viewer", "document:readme"),
+		tuple("group:staff#member", "member", "group:eng"),
+	)
+	c.Assert(err, qt.IsNil)
+	return fake
+}
+
+func TestFakeCheckRelation(t *testing.T) {
+	c := qt.New(t)
+
+	fake := newFake(c)
+	ctx := context.Background()
+
+	tests := []struct {
+		about            string
+		tuple            ofga.Tuple
+		contextualTuples []ofga.Tuple
+		expectedAllowed  bool
+		expectedErr      string
+	}{{
+		about:           "direct relation",
+		tuple:           tuple("user:bob", "viewer", "document:notes"),
+		expectedAllowed: true,
+	}, {
+		about:           "relation through nested usersets and a computed relation",
+		tuple:           tuple("user:alice", "viewer", "document:plan"),
+		expectedAllowed: true,
+	}, {
+		about:           "userset relation",
+		tuple:           tuple("group:eng#member", "editor", "document:plan"),
+		expectedAllowed: true,
+	}, {
+		about:           "public access",
+		tuple:           tuple("user:carol", "viewer", "document:readme"),
+		expectedAllowed: true,
+	}, {
+		about: "missing relation",
+		tuple: tuple("user:bob", "editor", "document:notes"),
+	}, {
+		about:            "contextual tuples",
+		tuple:            tuple("user:carol", "editor", "document:notes"),
+		contextualTuples: []ofga.Tuple{tuple("user:carol", "editor", "document:notes")},
+		expectedAllowed:  true,
+	}, {
+		about:       "unsupported relation definition",
+		tuple:       tuple("user:bob", "auditor", "document:notes"),
+		expectedErr: "cannot check relation: unsupported definition for relation document#auditor: only direct, computed and union relations are supported",
+	}, {
+		about:       "unknown relation",
+		tuple:       tuple("user:bob", "owner", "document:notes"),
+		expectedErr: "cannot check relation: relation document#owner not found in the authorization model",
+	}, {
+		about:       "unknown type",
+		tuple:       tuple("user:bob", "viewer", "folder:1"),
+		expectedErr: `cannot check relation: type "folder" not found in the authorization model`,
+	}, {
+		about:       "invalid tuple",
+		tuple:       ofga.Tuple{Object: &ofga.Entity{Kind: "user", ID: "bob"}, Relation: "viewer"},
+		expectedErr: "cannot check relation: invalid tuple user:bob#viewer@: missing tuple.Target",
+	}}
+
+	for _, test := range tests {
+		test := test
+		c.Run(test.about, func(c *qt.C) {
+			allowed, err := fake.CheckRelation(ctx, test.tuple, test.contextualTuples...)
+			if test.expectedErr != "" {
+				c.Assert(err, qt.ErrorMatches, test.expectedErr)
+			} else {
+				c.Assert(err, qt.IsNil)
+			}
+			c.Assert(allowed, qt.Equals, test.expectedAllowed)
+		})
+	}
+}
+
+func TestFakeCheckRelationWithoutModel(t *testing.T) {
+	c := qt.New(t)
+
+	fake := ofgatest.NewFake(nil)
+	ctx := context.Background()
+	err := fake.AddRelation(ctx, tuple("user:bob", "editor", "document:notes"))
+	c.Assert(err, qt.IsNil)
+
+	allowed, err := fake.CheckRelation(ctx, tuple("user:bob", "editor", "document:notes"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(allowed, qt.IsTrue)
+	allowed, err = fake.CheckRelation(ctx, tuple("user:bob", "viewer", "document:notes"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(allowed, qt.IsFalse)
+}
+
+func TestFakeAddRemoveRelations(t *testing.T) {
+	c := qt.New(t)
+
+	fake := newFake(c)
+	ctx := context.Background()
+
+	// Invalid writes are rejected without applying any change.
+	err := fake.AddRemoveRelations(ctx,
+		[]ofga.Tuple{tuple("user:carol", "viewer", "document:notes")},
+		[]ofga.Tuple{tuple("user:carol", "viewer", "document:readme")},
+	)
+	c.Assert(err, qt.ErrorMatches, "cannot add or remove relations: cannot delete a tuple which does not exist: user:carol#viewer@document:readme")
+	err = fake.AddRelation(ctx, tuple("user:dave", "viewer", "document:notes"), tuple("user:bob", "viewer", "document:notes"))
+	c.Assert(err, qt.ErrorMatches, "cannot add or remove relations: cannot write a tuple which already exists: user:bob#viewer@document:notes")
+	conditioned := tuple("user:carol", "viewer", "document:notes")
+	conditioned.Condition = &ofga.Condition{Name: "in_office_hours"}
+	err = fake.AddRelation(ctx, conditioned)
+	c.Assert(err, qt.ErrorMatches, "cannot add or remove relations: invalid tuple user:carol#viewer@document:notes: conditions are not supported")
+	tuples, _, err := fake.FindMatchingTuples(ctx, ofga.Tuple{Target: &ofga.Entity{Kind: "document", ID: "notes"}}, 0, "")
+	c.Assert(err, qt.IsNil)
+	c.Assert(ofga.StripTimestamps(tuples), qt.DeepEquals, []ofga.Tuple{tuple("user:bob", "viewer", "document:notes")})
+
+	// A tuple can be replaced by another one.
+	err = fake.AddRemoveRelations(ctx,
+		[]ofga.Tuple{tuple("user:carol", "viewer", "document:notes")},
+		[]ofga.Tuple{tuple("user:bob", "viewer", "document:notes")},
+	)
+	c.Assert(err, qt.IsNil)
+	allowed, err := fake.CheckRelation(ctx, tuple("user:bob", "viewer", "document:notes"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(allowed, qt.IsFalse)
+	allowed, err = fake.CheckRelation(ctx, tuple("user:carol", "viewer", "document:notes"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(allowed, qt.IsTrue)
+
+	err = fake.RemoveRelation(ctx, tuple("user:carol", "viewer", "document:notes"))
+	c.Assert(err, qt.IsNil)
+	tuples, _, err = fake.FindMatchingTuples(ctx, ofga.Tuple{Target: &ofga.Entity{Kind: "document", ID: "notes"}}, 0, "")
+	c.Assert(err, qt.IsNil)
+	c.Assert(tuples, qt.HasLen, 0)
+}
+
+func TestFakeFindMatchingTuples(t *testing.T) {
+	c := qt.New(t)
+
+	fake := newFake(c)
+	ctx := context.Background()
+
+	tuples, token, err := fake.FindMatchingTuples(ctx, query("", "member", "group"), 10, "")
+	c.Assert(err, qt.IsNil)
+	c.Assert(token, qt.Equals, "")
+	c.Assert(ofga.StripTimestamps(tuples), qt.DeepEquals, []ofga.Tuple{
+		tuple("user:alice", "member", "group:eng"),
+		tuple("group:eng#member", "member", "group:staff"),
+		tuple("group:staff#member", "member", "group:eng"),
+	})
+	for _, t := range tuples {
+		c.Assert(t.HasTimestamp, qt.IsTrue)
+	}
+
+	tuples, _, err = fake.FindMatchingTuples(ctx, query("group:staff#member", "", "document"), 10, "")
+	c.Assert(err, qt.IsNil)
+	c.Assert(ofga.StripTimestamps(tuples), qt.DeepEquals, []ofga.Tuple{
+		tuple("group:staff#member", "editor", "document:plan"),
+	})
+}
+
+func TestFakeFindAccessibleObjectsByRelation(t *testing.T) {
+	c := qt.New(t)
+
+	fake := newFake(c)
+	ctx := context.Background()
+
+	objects, err := fake.FindAccessibleObjectsByRelation(ctx, query("user:alice", "viewer", "document"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(objects, qt.DeepEquals, []ofga.Entity{
+		{Kind: "document", ID: "plan"},
+		{Kind: "document", ID: "readme"},
+	})
+
+	objects, err = fake.FindAccessibleObjectsByRelation(ctx, query("user:bob", "viewer", "document"), tuple("user:bob", "editor", "document:draft"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(objects, qt.DeepEquals, []ofga.Entity{
+		{Kind: "document", ID: "draft"},
+		{Kind: "document", ID: "notes"},
+		{Kind: "document", ID: "readme"},
+	})
+
+	_, err = fake.FindAccessibleObjectsByRelation(ctx, tuple("user:alice", "viewer", "document:plan"))
+	c.Assert(err, qt.ErrorMatches, "invalid tuple for FindAccessibleObjectsByRelation: only tuple.Target.Kind must be set")
+}
+
+func TestFakeIdempotentWrites(t *testing.T) {
+	c := qt.New(t)
+
+	fake := newFake(c)
+	ctx := context.Background()
+
+	created, err := fake.AddRelationIfAbsent(ctx, tuple("user:bob", "viewer", "document:notes"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(created, qt.IsFalse)
+	created, err = fake.AddRelationIfAbsent(ctx, tuple("user:carol", "viewer", "document:notes"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(created, qt.IsTrue)
+
+	err = fake.SetRelation(ctx, tuple("user:carol", "viewer", "document:notes"), false)
+	c.Assert(err, qt.IsNil)
+	err = fake.SetRelation(ctx, tuple("user:carol", "viewer", "document:notes"), false)
+	c.Assert(err, qt.IsNil)
+	err = fake.SetRelation(ctx, tuple("user:dave", "viewer", "document:notes"), true)
+	c.Assert(err, qt.IsNil)
+
+	removed, err := fake.RemoveRelationBatched(ctx, []ofga.Tuple{
+		tuple("user:dave", "viewer", "document:notes"),
+		tuple("user:erin", "viewer", "document:notes"),
+	}, 1)
+	c.Assert(err, qt.IsNil)
+	c.Assert(removed, qt.Equals, 1)
+
+	tuples, _, err := fake.FindMatchingTuples(ctx, ofga.Tuple{Target: &ofga.Entity{Kind: "document", ID: "notes"}}, 0, "")
+	c.Assert(err, qt.IsNil)
+	c.Assert(ofga.StripTimestamps(tuples), qt.DeepEquals, []ofga.Tuple{tuple("user:bob", "viewer", "document:notes")})
+}
+
+func TestFakeReconcileTuples(t *testing.T) {
+	c := qt.New(t)
+
+	fake := ofgatest.NewFake(nil)
+	ctx := context.Background()
+	err := fake.AddRelation(ctx, tuple("user:bob", "viewer", "document:notes"), tuple("user:alice", "viewer", "document:notes"))
+	c.Assert(err, qt.IsNil)
+
+	added, removed, err := fake.ReconcileTuples(ctx, []ofga.Tuple{
+		tuple("user:alice", "viewer", "document:notes"),
+		tuple("user:carol", "viewer", "document:notes"),
+	})
+	c.Assert(err, qt.IsNil)
+	c.Assert(added, qt.Equals, 1)
+	c.Assert(removed, qt.Equals, 1)
+	tuples, _, err := fake.FindMatchingTuples(ctx, ofga.Tuple{}, 0, "")
+	c.Assert(err, qt.IsNil)
+	c.Assert(ofga.StripTimestamps(tuples), qt.DeepEquals, []ofga.Tuple{
+		tuple("user:alice", "viewer", "document:notes"),
+		tuple("user:carol", "viewer", "document:notes"),
+	})
+}
+
+func TestFakeClone(t *testing.T) {
+	c := qt.New(t)
+
+	fake := newFake(c)
+	fake.SetStoreID("store-1")
+	clone := fake.Clone()
+	clone.SetStoreID("store-2")
+	c.Assert(fake.StoreID(), qt.Equals, "store-1")
+	c.Assert(clone.StoreID(), qt.Equals, "store-2")
+	hasStore, hasModel := clone.IsConfigured()
+	c.Assert(hasStore, qt.IsTrue)
+	c.Assert(hasModel, qt.IsFalse)
+
+	// Tuples are shared with the clone.
+	ctx := context.Background()
+	err := clone.AddRelation(ctx, tuple("user:carol", "viewer", "document:notes"))
+	c.Assert(err, qt.IsNil)
+	allowed, err := fake.CheckRelation(ctx, tuple("user:carol", "viewer", "document:notes"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(allowed, qt.IsTrue)
+}
+
+func TestFakeModel(t *testing.T) {
+	c := qt.New(t)
+
+	model, err := ofga.AuthModelFromDSL([]byte(`model
+  schema 1.1
+
+type user
+
+type document
+  relations
+    define editor: [user]
+    define viewer: [user, user:*] or editor
+`))
+	c.Assert(err, qt.IsNil)
+	fake := ofgatest.NewFake(model)
+	ctx := context.Background()
+	err = fake.AddRelation(ctx,
+		tuple("user:alice", "editor", "document:plan"),
+		tuple("user:*", "viewer", "document:readme"),
+	)
+	c.Assert(err, qt.IsNil)
+
+	grants, err := fake.FindUserGrants(ctx, ofga.Entity{Kind: "user", ID: "alice"}, "document")
+	c.Assert(err, qt.IsNil)
+	c.Assert(grants, qt.DeepEquals, map[ofga.Relation][]ofga.Entity{
+		"editor": {{Kind: "document", ID: "plan"}},
+		"viewer": {{Kind: "document", ID: "plan"}, {Kind: "document", ID: "readme"}},
+	})
+
+	err = fake.AssertModelHasRelations(ctx, map[ofga.Kind][]ofga.Relation{
+		"document": {"viewer", "owner"},
+		"folder":   nil,
+	})
+	c.Assert(err, qt.ErrorMatches, "authorization model  is missing relation document#owner, type folder")
+
+	_, err = ofgatest.NewFake(nil).GetAuthModel(ctx, "")
+	c.Assert(err, qt.ErrorMatches, "no authorization model found")
+}
+
+func TestFakeUnsupported(t *testing.T) {
+	c := qt.New(t)
+
+	fake := newFake(c)
+	ctx := context.Background()
+
+	_, err := fake.CreateStore(ctx, "test")
+	c.Assert(err, qt.ErrorIs, ofgatest.ErrUnsupported)
+	c.Assert(err, qt.ErrorMatches, "cannot call CreateStore: not supported by the fake client")
+
+	changes, errs := fake.StreamChanges(ctx, "document", time.Second)
+	_, ok := <-changes
+	c.Assert(ok, qt.IsFalse)
+	c.Assert(<-errs, qt.ErrorIs, ofgatest.ErrUnsupported)
+}
+
+func TestFakeExportImportTuples(t *testing.T) {
+	c := qt.New(t)
+
+	fake := newFake(c)
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	n, err := fake.ExportTuplesWithOptions(ctx, &buf, ofga.ExportTuplesOptions{Kinds: []ofga.Kind{"document"}})
+	c.Assert(err, qt.IsNil)
+	c.Assert(n, qt.Equals, 3)
+	c.Assert(buf.String(), qt.Equals, `{"object":"group:staff#member","relation":"editor","target":"document:plan"}
+{"object":"user:bob","relation":"viewer","target":"document:notes"}
+{"object":"user:*","relation":"viewer","target":"document:readme"}
+`)
+
+	imported := ofgatest.NewFake(nil)
+	err = imported.AddRelation(ctx, tuple("user:bob", "viewer", "document:notes"))
+	c.Assert(err, qt.IsNil)
+	n, err = imported.ImportTuples(ctx, &buf, 10)
+	c.Assert(err, qt.IsNil)
+	c.Assert(n, qt.Equals, 3)
+	tuples, _, err := imported.FindMatchingTuples(ctx, ofga.Tuple{}, 0, "")
+	c.Assert(err, qt.IsNil)
+	c.Assert(ofga.StripTimestamps(tuples), qt.DeepEquals, []ofga.Tuple{
+		tuple("user:bob", "viewer", "document:notes"),
+		tuple("group:staff#member", "editor", "document:plan"),
+		tuple("user:*", "viewer", "document:readme"),
+	})
+
+	_, err = imported.ImportTuples(ctx, strings.NewReader(`{"object":"user:bob","relation":"viewer"}`), 10)
+	c.Assert(err, qt.ErrorMatches, "invalid tuple on line 1: invalid tuple target: .*")
+}
+
+func TestFakeFindUsersByRelation(t *testing.T) {
+	c := qt.New(t)
+
+	fake := newFake(c)
+	ctx := context.Background()
+
+	users, err := fake.FindUsersByRelation(ctx, ofga.Tuple{Relation: "viewer", Target: &ofga.Entity{Kind: "document", ID: "plan"}}, 1)
+	c.Assert(err, qt.IsNil)
+	c.Assert(users, qt.DeepEquals, []ofga.Entity{{Kind: "user", ID: "alice"}})
+
+	users, err = fake.FindUsersByRelation(ctx, ofga.Tuple{Relation: "viewer", Target: &ofga.Entity{Kind: "document", ID: "readme"}}, 1)
+	c.Assert(err, qt.IsNil)
+	c.Assert(users, qt.DeepEquals, []ofga.Entity{{Kind: "user", ID: "*"}})
+
+	_, err = fake.FindUsersByRelation(ctx, ofga.Tuple{Relation: "viewer", Target: &ofga.Entity{Kind: "document", ID: "plan"}}, 0)
+	c.Assert(err, qt.ErrorMatches, "maxDepth must be greater than or equal to 1")
+}
+
+func TestFakeListUsers(t *testing.T) {
+	c := qt.New(t)
+
+	fake := newFake(c)
+	ctx := context.Background()
+	target := &ofga.Entity{Kind: "document", ID: "plan"}
+
+	users, err := fake.ListUsers(ctx, ofga.Tuple{
+		Object:   &ofga.Entity{Kind: "user"},
+		Relation: "viewer",
+		Target:   target,
+	}, ofga.ListUsersOptions{
+		ContextualTuples: []ofga.Tuple{tuple("user:bob", "editor", "document:plan")},
+	})
+	c.Assert(err, qt.IsNil)
+	c.Assert(users, qt.DeepEquals, []ofga.Entity{{Kind: "user", ID: "alice"}, {Kind: "user", ID: "bob"}})
+
+	users, err = fake.ListUsers(ctx, ofga.Tuple{
+		Object:   &ofga.Entity{Kind: "group", Relation: "member"},
+		Relation: "editor",
+		Target:   target,
+	}, ofga.ListUsersOptions{DirectOnly: true})
+	c.Assert(err, qt.IsNil)
+	c.Assert(users, qt.DeepEquals, []ofga.Entity{{Kind: "group", ID: "staff", Relation: "member"}})
+
+	_, err = fake.ListUsers(ctx, tuple("user:alice", "viewer", "document:plan"), ofga.ListUsersOptions{})
+	c.Assert(err, qt.ErrorMatches, "invalid tuple for ListUsers: only tuple.Object.Kind and optionally tuple.Object.Relation must be set")
+}
+
+// TestFakeMethods checks that the Fake implements all the methods of
+// ofga.Client, so that it can be used in place of a client in tests.
+func TestFakeMethods(t *testing.T) {
+	c := qt.New(t)
+
+	clientType := reflect.TypeOf((*ofga.Client)(nil))
+	fakeType := reflect.TypeOf((*ofgatest.Fake)(nil))
+	for i := 0; i < clientType.NumMethod(); i++ {
+		method := clientType.Method(i)
+		if method.Name == "Clone" {
+			// Clone returns the concrete type of the client.
+			continue
+		}
+		fakeMethod, ok := fakeType.MethodByName(method.Name)
+		if !ok {
+			c.Errorf("method %s not implemented by the fake", method.Name)
+			continue
+		}
+		c.Check(signature(fakeMethod.Type), qt.Equals, signature(method.Type), qt.Commentf("method %s", method.Name))
+	}
+}
+
+// signature returns the string representation of the given method type,
+// excluding the receiver.
+func signature(t reflect.Type) string {
+	var in, out []string
+	for i := 1; i < t.NumIn(); i++ {
+		in = append(in, t.In(i).String())
+	}
+	for i := 0; i < t.NumOut(); i++ {
+		out = append(out, t.Out(i).String())
+	}
+	if t.IsVariadic() {
+		in[len(in)-1] = "..." + strings.TrimPrefix(in[len(in)-1], "[]")
+	}
+	return "(" + strings.Join(in, ", ") + ") (" + strings.Join(out, ", ") + ")"
+}
@@ -0,0 +1,108 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the LGPL license, see LICENSE file for details.
+
+package ofgatest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	openfga "github.com/openfga/go-sdk"
+
+	"github.com/canonical/ofga"
+)
+
+// ErrUnsupported is returned, wrapped, by the methods of the Fake that
+// require an OpenFGA server.
+var ErrUnsupported = errors.New("not supported by the fake client")
+
+// unsupported returns an error wrapping ErrUnsupported for the given method.
+func unsupported(method string) error {
+	return fmt.Errorf("cannot call %s: %w", method, ErrUnsupported)
+}
+
+// ExpandTree is not supported and returns an error wrapping ErrUnsupported.
+func (f *Fake) ExpandTree(ctx context.Context, tuple ofga.Tuple) (*openfga.UsersetTree, error) {
+	return nil, unsupported("ExpandTree")
+}
+
+// CreateStore is not supported and returns an error wrapping ErrUnsupported.
+func (f *Fake) CreateStore(ctx context.Context, name string) (string, error) {
+	return "", unsupported("CreateStore")
+}
+
+// ListStores is not supported and returns an error wrapping ErrUnsupported.
+func (f *Fake) ListStores(ctx context.Context, pageSize int32, continuationToken string) (openfga.ListStoresResponse, error) {
+	return openfga.ListStoresResponse{}, unsupported("ListStores")
+}
+
+// StoreInfo is not supported and returns an error wrapping ErrUnsupported.
+func (f *Fake) StoreInfo(ctx context.Context) (ofga.StoreInfo, error) {
+	return ofga.StoreInfo{}, unsupported("StoreInfo")
+}
+
+// StoreSummary is not supported and returns an error wrapping ErrUnsupported.
+func (f *Fake) StoreSummary(ctx context.Context) (ofga.StoreSummary, error) {
+	return ofga.StoreSummary{}, unsupported("StoreSummary")
+}
+
+// ReadChanges is not supported and returns an error wrapping ErrUnsupported.
+func (f *Fake) ReadChanges(ctx context.Context, entityType string, pageSize int32, continuationToken string) (openfga.ReadChangesResponse, error) {
+	return openfga.ReadChangesResponse{}, unsupported("ReadChanges")
+}
+
+// ReadChangesForKind is not supported and returns an error wrapping
+// ErrUnsupported.
+func (f *Fake) ReadChangesForKind(ctx context.Context, kind ofga.Kind, pageSize int32, continuationToken string) (openfga.ReadChangesResponse, error) {
+	return openfga.ReadChangesResponse{}, unsupported("ReadChangesForKind")
+}
+
+// ReadChangesTyped is not supported and returns an error wrapping
+// ErrUnsupported.
+func (f *Fake) ReadChangesTyped(ctx context.Context, entityType string, pageSize int32, continuationToken string) ([]openfga.TupleChange, string, error) {
+	return nil, "", unsupported("ReadChangesTyped")
+}
+
+// StreamChanges is not supported: the returned changes channel is closed, and
+// an error wrapping ErrUnsupported is sent on the errors channel, which is
+// then closed.
+func (f *Fake) StreamChanges(ctx context.Context, entityType string, pollInterval time.Duration) (<-chan openfga.TupleChange, <-chan error) {
+	changes := make(chan openfga.TupleChange)
+	close(changes)
+	errs := make(chan error, 1)
+	errs <- unsupported("StreamChanges")
+	close(errs)
+	return changes, errs
+}
+
+// CreateAuthModel is not supported and returns an error wrapping
+// ErrUnsupported.
+func (f *Fake) CreateAuthModel(ctx context.Context, authModel *openfga.AuthorizationModel) (string, error) {
+	return "", unsupported("CreateAuthModel")
+}
+
+// CreateAuthModelFromFile is not supported and returns an error wrapping
+// ErrUnsupported.
+func (f *Fake) CreateAuthModelFromFile(ctx context.Context, path string) (string, error) {
+	return "", unsupported("CreateAuthModelFromFile")
+}
+
+// CreateAuthModelFull is not supported and returns an error wrapping
+// ErrUnsupported.
+func (f *Fake) CreateAuthModelFull(ctx context.Context, authModel *openfga.AuthorizationModel) (openfga.AuthorizationModel, error) {
+	return openfga.AuthorizationModel{}, unsupported("CreateAuthModelFull")
+}
+
+// CreateAuthModelValidated is not supported and returns an error wrapping
+// ErrUnsupported.
+func (f *Fake) CreateAuthModelValidated(ctx context.Context, authModel *openfga.AuthorizationModel, assertions []openfga.Assertion) (string, error) {
+	return "", unsupported("CreateAuthModelValidated")
+}
+
+// WriteAssertionsFromFile is not supported and returns an error wrapping
+// ErrUnsupported.
+func (f *Fake) WriteAssertionsFromFile(ctx context.Context, authModelID, path string) error {
+	return unsupported("WriteAssertionsFromFile")
+}
@@ -4,16 +4,40 @@
 package ofga
 
 import (
+	"errors"
 	"fmt"
+	"net/url"
 	"regexp"
+	"slices"
+	"strings"
 	"time"
 
 	openfga "github.com/openfga/go-sdk"
 )
 
-// entityRegex is used to validate that a string represents an Entity/EntitySet
-// and helps to convert from a string representation into an Entity struct.
-var entityRegex = regexp.MustCompile(`([A-Za-z0-9_][A-Za-z0-9_-]*):([A-Za-z0-9_][A-Za-z0-9_@.+-]*|[*])(#([A-Za-z0-9_][A-Za-z0-9_-]*))?$`)
+// Patterns matching the individual components of an Entity/EntitySet.
+const (
+	kindPattern     = `[A-Za-z0-9_][A-Za-z0-9_-]*`
+	idPattern       = `[A-Za-z0-9_%][A-Za-z0-9_@.+%-]*|[*]`
+	relationPattern = `[A-Za-z0-9_][A-Za-z0-9_-]*`
+)
+
+var (
+	// entityRegex is used to validate that a string represents an
+	// Entity/EntitySet and helps to convert from a string representation into
+	// an Entity struct.
+	entityRegex = regexp.MustCompile(`^(` + kindPattern + `):(` + idPattern + `)(#(` + relationPattern + `))?$`)
+
+	// kindRegex, idRegex and relationRegex are used to validate the
+	// individual components of an Entity/EntitySet.
+	kindRegex     = regexp.MustCompile(`^(?:` + kindPattern + `)$`)
+	idRegex       = regexp.MustCompile(`^(?:` + idPattern + `)$`)
+	relationRegex = regexp.MustCompile(`^(?:` + relationPattern + `)$`)
+
+	// relationNameRegex is used by Relation.Validate to enforce the OpenFGA
+	// relation naming rules, which are stricter than relationPattern.
+	relationNameRegex = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+)
 
 // Kind represents the type of the entity in OpenFGA.
 type Kind string
@@ -31,19 +55,76 @@ func (r Relation) String() string {
 	return string(r)
 }
 
+// Validate checks that the relation follows the OpenFGA relation naming
+// rules: it must start with a lowercase letter and only contain lowercase
+// letters, digits and underscores. Validating relations before sending them
+// to the server results in a more precise error than the one returned by the
+// server for invalid relations.
+func (r Relation) Validate() error {
+	if r == "" {
+		return errors.New("empty relation")
+	}
+	if !relationNameRegex.MatchString(string(r)) {
+		return fmt.Errorf("invalid relation %q: relations must start with a lowercase letter and only contain lowercase letters, digits and underscores", r)
+	}
+	return nil
+}
+
 // Entity represents an entity/entity-set in OpenFGA.
 // Example: `user:<user-id>`, `org:<org-id>#member`
+//
+// IDs containing characters that are not allowed in entity IDs (such as `:` or
+// `#`) can be encoded using EncodeID, and decoded using DecodeID.
 type Entity struct {
 	Kind     Kind
 	ID       string
 	Relation Relation
 }
 
+// PublicAccess returns an entity representing any entity of the given kind
+// (i.e. the `<kind>:*` wildcard). It can be used as the object of a tuple to
+// grant a relation to everyone, e.g. to make a document viewable by all
+// users:
+//
+//	client.AddRelation(ctx, Tuple{
+//		Object:   PublicAccess("user"),
+//		Relation: "viewer",
+//		Target:   &Entity{Kind: "document", ID: "planning"},
+//	})
+//
+// Note that the authorization model must allow the wildcard as a directly
+// related user type for the relation (e.g. `define viewer: [user, user:*]`),
+// otherwise the server rejects the tuple.
+func PublicAccess(kind Kind) *Entity {
+	return &Entity{Kind: kind, ID: "*"}
+}
+
 // IsPublicAccess returns true when the entity ID is the * wildcard, representing any entity.
 func (e *Entity) IsPublicAccess() bool {
 	return e.ID == "*"
 }
 
+// IsEntitySet returns true when the entity represents the set of entities
+// that have a specific relation with it (e.g. `organization:canonical#member`).
+func (e *Entity) IsEntitySet() bool {
+	return e.Relation != ""
+}
+
+// validate checks that the components of the entity are well-formed, as
+// required by ParseEntity.
+func (e *Entity) validate() error {
+	if !kindRegex.MatchString(e.Kind.String()) {
+		return fmt.Errorf("invalid entity kind %q", e.Kind)
+	}
+	if !idRegex.MatchString(e.ID) {
+		return fmt.Errorf("invalid entity ID %q", e.ID)
+	}
+	if e.Relation != "" && !relationRegex.MatchString(e.Relation.String()) {
+		return fmt.Errorf("invalid entity relation %q", e.Relation)
+	}
+	return nil
+}
+
 // String returns a string representation of the entity/entity-set.
 func (e *Entity) String() string {
 	if e.Relation == "" {
@@ -52,15 +133,58 @@ func (e *Entity) String() string {
 	return e.Kind.String() + ":" + e.ID + "#" + e.Relation.String()
 }
 
+// NewEntity returns an Entity with the specified kind and ID, after
+// validating that both are well-formed.
+func NewEntity(kind Kind, id string) (Entity, error) {
+	if !kindRegex.MatchString(string(kind)) {
+		return Entity{}, fmt.Errorf("invalid entity kind: %q", kind)
+	}
+	if !idRegex.MatchString(id) {
+		return Entity{}, fmt.Errorf("invalid entity ID: %q", id)
+	}
+	return Entity{Kind: kind, ID: id}, nil
+}
+
+// NewEntitySet returns an Entity representing the set of entities that have
+// the specified relation with the entity identified by kind and ID (e.g.
+// `organization:canonical#member`), after validating that all components are
+// well-formed.
+func NewEntitySet(kind Kind, id string, relation Relation) (Entity, error) {
+	e, err := NewEntity(kind, id)
+	if err != nil {
+		return Entity{}, err
+	}
+	if !relationRegex.MatchString(string(relation)) {
+		return Entity{}, fmt.Errorf("invalid entity relation: %q", relation)
+	}
+	e.Relation = relation
+	return e, nil
+}
+
 // ParseEntity will parse a string representation into an Entity. It expects to
 // find entities of the form:
 //   - <entityType>:<ID>
 //     eg. organization:canonical
 //   - <entityType>:<ID>#<relationship-set>
 //     eg. organization:canonical#member
+//
+// Entity types and relations are made of letters, digits, `_` and `-`, and
+// cannot start with `-`. IDs are either the `*` wildcard, or made of letters,
+// digits and the `_`, `@`, `.`, `+`, `-` and `%` characters, and cannot start
+// with `@`, `.`, `+` or `-`. In particular, `:` and `#` are reserved as
+// separators and cannot be part of an ID: strings using them in the ID are
+// rejected, as they would otherwise be misinterpreted (for instance, the `b`
+// in `user:a#b` is always parsed as a relation). Use EncodeID to encode IDs
+// containing reserved characters.
 func ParseEntity(s string) (Entity, error) {
 	match := entityRegex.FindStringSubmatch(s)
 	if match == nil {
+		if kind, rest, ok := strings.Cut(s, ":"); ok && kindRegex.MatchString(kind) {
+			id, relation, _ := strings.Cut(rest, "#")
+			if strings.Contains(id, ":") || strings.Contains(relation, "#") {
+				return Entity{}, fmt.Errorf("invalid entity representation: %s: entity IDs cannot contain the reserved %q and %q characters, use EncodeID to encode them", s, ":", "#")
+			}
+		}
 		return Entity{}, fmt.Errorf("invalid entity representation: %s", s)
 	}
 
@@ -72,6 +196,70 @@ func ParseEntity(s string) (Entity, error) {
 	}, nil
 }
 
+// ParseEntityPrefix behaves like ParseEntity, but also accepts a type-only
+// representation with an empty ID, such as `document:`, which is returned as
+// an Entity with only the Kind set. This is how targets are specified in read
+// queries matching all the objects of a type (see FindMatchingTuples), so
+// ParseEntityPrefix is useful to build such queries from external input. The
+// resulting entities must not be used where a full entity is required, e.g.
+// when adding relations.
+func ParseEntityPrefix(s string) (Entity, error) {
+	if kind, ok := strings.CutSuffix(s, ":"); ok && kindRegex.MatchString(kind) {
+		return Entity{Kind: Kind(kind)}, nil
+	}
+	return ParseEntity(s)
+}
+
+// ParseEntities parses the given string representations into entities, as
+// done by ParseEntity. Parsing does not stop at the first invalid string: the
+// returned slices have the same length as ss, and for each string hold either
+// the parsed entity and a nil error, or the zero Entity and the error
+// explaining why the string could not be parsed. This is useful, for
+// instance, to report all the invalid entries of a batch from external input.
+func ParseEntities(ss []string) ([]Entity, []error) {
+	entities := make([]Entity, len(ss))
+	errs := make([]error, len(ss))
+	for i, s := range ss {
+		entities[i], errs[i] = ParseEntity(s)
+	}
+	return entities, errs
+}
+
+// EncodeID percent-encodes the characters of the given ID that cannot be
+// used as part of an entity ID (such as `:`, `#` or `/`), so that the result
+// can be safely used as the ID of an Entity. Characters that are already
+// valid in entity IDs are left untouched, which means that encoding simple
+// IDs returns them unchanged. Use DecodeID to retrieve the original ID.
+// Example: EncodeID("urn:isbn:0451450523") returns "urn%3Aisbn%3A0451450523".
+func EncodeID(id string) string {
+	var b strings.Builder
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if isIDChar(c) {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+// DecodeID decodes an ID previously encoded with EncodeID.
+func DecodeID(id string) (string, error) {
+	decoded, err := url.PathUnescape(id)
+	if err != nil {
+		return "", fmt.Errorf("invalid encoded entity ID: %q", id)
+	}
+	return decoded, nil
+}
+
+// isIDChar reports whether the given character can be used as part of an
+// entity ID without being encoded.
+func isIDChar(c byte) bool {
+	return 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+		strings.IndexByte("_@.+-", c) >= 0
+}
+
 // Tuple represents a relation between an object and a target. Note that OpenFGA
 // represents a Tuple as (User, Relation, Object). However, the `User` field is
 // not restricted to just being users, it could also refer to objects when we
@@ -81,6 +269,140 @@ type Tuple struct {
 	Object   *Entity
 	Relation Relation
 	Target   *Entity
+	// Condition optionally specifies a condition that must be satisfied for
+	// the relation to hold.
+	Condition *Condition
+}
+
+// Condition represents a condition attached to a relationship tuple. The
+// condition must be defined in the authorization model.
+type Condition struct {
+	// Name references the condition as defined in the authorization model.
+	Name string
+	// Context holds the parameters to be stored along with the condition.
+	// The keys must match the parameters defined by the condition.
+	Context map[string]any
+}
+
+// toOpenFGARelationshipCondition converts our Condition struct into an
+// OpenFGA RelationshipCondition.
+func (c *Condition) toOpenFGARelationshipCondition() *openfga.RelationshipCondition {
+	rc := openfga.NewRelationshipCondition(c.Name)
+	if c.Context != nil {
+		rc.SetContext(c.Context)
+	}
+	return rc
+}
+
+// fromOpenFGARelationshipCondition converts an OpenFGA RelationshipCondition
+// into a Condition.
+func fromOpenFGARelationshipCondition(rc *openfga.RelationshipCondition) *Condition {
+	return &Condition{
+		Name:    rc.GetName(),
+		Context: rc.GetContext(),
+	}
+}
+
+// IsUsersetGrant returns true when the tuple grants the relation to a set of
+// entities (e.g. `group:eng#member`) rather than to a single entity.
+func (t Tuple) IsUsersetGrant() bool {
+	return t.Object != nil && t.Object.IsEntitySet()
+}
+
+// Validate checks that the tuple can be written: its object, relation and
+// target must be specified and well-formed, the target must not be an entity
+// set, and its condition, if any, must have a name. Note that the tuple is
+// not validated against an authorization model (see
+// ValidateTuplesAgainstModel).
+func (t Tuple) Validate() error {
+	if t.Object == nil {
+		return errors.New("missing object")
+	}
+	if err := t.Object.validate(); err != nil {
+		return fmt.Errorf("invalid tuple object: %v", err)
+	}
+	if t.Relation == "" {
+		return errors.New("missing relation")
+	}
+	if !relationRegex.MatchString(t.Relation.String()) {
+		return fmt.Errorf("invalid tuple relation: %q", t.Relation)
+	}
+	if t.Target == nil {
+		return errors.New("missing target")
+	}
+	if err := t.Target.validate(); err != nil {
+		return fmt.Errorf("invalid tuple target: %v", err)
+	}
+	if t.Target.Relation != "" {
+		return errors.New("target must not be an entity set")
+	}
+	if t.Condition != nil && t.Condition.Name == "" {
+		return errors.New("missing condition name")
+	}
+	return nil
+}
+
+// String returns a string representation of the tuple in the form
+// `<object>#<relation>@<target>` (e.g. `user:123#editor@document:abc`). Unset
+// components are represented by empty strings. Use ParseTupleString to parse
+// the resulting string.
+func (t Tuple) String() string {
+	var object, target string
+	if t.Object != nil {
+		object = t.Object.String()
+	}
+	if t.Target != nil {
+		target = t.Target.String()
+	}
+	return object + "#" + t.Relation.String() + "@" + target
+}
+
+// ParseTuple builds a Tuple from the string representations of its object,
+// relation and target. The object and target are parsed as described in
+// ParseEntity. The object and relation may be empty, which is useful to build
+// tuples used as queries (e.g. in FindMatchingTuples), in which case they are
+// left unset in the returned tuple.
+func ParseTuple(object, relation, target string) (Tuple, error) {
+	var t Tuple
+	if object != "" {
+		o, err := ParseEntity(object)
+		if err != nil {
+			return Tuple{}, fmt.Errorf("invalid tuple object: %v", err)
+		}
+		t.Object = &o
+	}
+	if relation != "" {
+		if !relationRegex.MatchString(relation) {
+			return Tuple{}, fmt.Errorf("invalid tuple relation: %q", relation)
+		}
+		t.Relation = Relation(relation)
+	}
+	tg, err := ParseEntity(target)
+	if err != nil {
+		return Tuple{}, fmt.Errorf("invalid tuple target: %v", err)
+	}
+	t.Target = &tg
+	return t, nil
+}
+
+// ParseTupleString builds a Tuple from its string representation, as
+// returned by Tuple.String (e.g. `team:eng#member#editor@contract:789`). The
+// object and relation may be empty, as described in ParseTuple. The condition
+// of a tuple is not part of its string representation and is left unset.
+func ParseTupleString(s string) (Tuple, error) {
+	// Entity IDs cannot contain "#", and relations cannot contain "#" or "@",
+	// so the separator preceding the relation is the "#" followed by a valid
+	// relation and an "@".
+	for i := 0; i < len(s); i++ {
+		if s[i] != '#' {
+			continue
+		}
+		relation, target, ok := strings.Cut(s[i+1:], "@")
+		if ok && (relation == "" || relationRegex.MatchString(relation)) {
+			return ParseTuple(s[:i], relation, target)
+		}
+	}
+	return Tuple{}, fmt.Errorf("invalid tuple representation: %s", s)
 }
 
 // ToOpenFGATupleKey converts our Tuple struct into an OpenFGA TupleKey.
@@ -94,7 +416,14 @@ func (t Tuple) ToOpenFGATupleKey() *openfga.TupleKey {
 	if t.Relation != "" {
 		k.SetRelation(t.Relation.String())
 	}
-	k.SetObject(t.Target.String())
+	// The target may be omitted when reading tuples, e.g. to read all the
+	// tuples for a given user.
+	if t.Target != nil {
+		k.SetObject(t.Target.String())
+	}
+	if t.Condition != nil {
+		k.SetCondition(*t.Condition.toOpenFGARelationshipCondition())
+	}
 	return k
 }
 
@@ -152,15 +481,24 @@ func FromOpenFGATupleKey(key openfga.TupleKey) (Tuple, error) {
 		}
 	}
 
+	var condition *Condition
+	if key.HasCondition() {
+		condition = fromOpenFGARelationshipCondition(key.Condition)
+	}
+
 	return Tuple{
-		Object:   &user,
-		Relation: Relation(key.GetRelation()),
-		Target:   &object,
+		Object:    &user,
+		Relation:  Relation(key.GetRelation()),
+		Target:    &object,
+		Condition: condition,
 	}, nil
 }
 
-// tuplesToOpenFGATupleKeys converts a slice of tuples into OpenFGA TupleKeys.
-func tuplesToOpenFGATupleKeys(tuples []Tuple) []openfga.TupleKey {
+// TuplesToTupleKeys converts a slice of tuples into OpenFGA TupleKeys, as
+// done by ToOpenFGATupleKey for each tuple. Conditions are preserved. This is
+// useful to build requests that are not supported by the Client, using the
+// underlying OpenFGA client directly.
+func TuplesToTupleKeys(tuples []Tuple) []openfga.TupleKey {
 	keys := make([]openfga.TupleKey, len(tuples))
 	for i, tuple := range tuples {
 		keys[i] = *tuple.ToOpenFGATupleKey()
@@ -168,6 +506,31 @@ func tuplesToOpenFGATupleKeys(tuples []Tuple) []openfga.TupleKey {
 	return keys
 }
 
+// tuplesToOpenFGATupleKeys converts a slice of tuples into OpenFGA TupleKeys.
+func tuplesToOpenFGATupleKeys(tuples []Tuple) []openfga.TupleKey {
+	return TuplesToTupleKeys(tuples)
+}
+
+// contextualTuplesToOpenFGATupleKeys converts a slice of contextual tuples
+// into OpenFGA TupleKeys, removing duplicate tuples (compared by their string
+// representation) while preserving the order of their first occurrence.
+// Duplicate contextual tuples may cause requests to be rejected by the
+// server, and they are easily introduced when contextual tuples are gathered
+// from multiple sources.
+func contextualTuplesToOpenFGATupleKeys(tuples []Tuple) []openfga.TupleKey {
+	seen := make(map[string]bool, len(tuples))
+	keys := make([]openfga.TupleKey, 0, len(tuples))
+	for _, tuple := range tuples {
+		key := tuple.String()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, *tuple.ToOpenFGATupleKey())
+	}
+	return keys
+}
+
 // tuplesToOpenFGATupleKeysWithoutCondition converts a slice of tuples into
 // a slice of OpenFGA TupleKeyWithoutCondition.
 func tuplesToOpenFGATupleKeysWithoutCondition(tuples []Tuple) []openfga.TupleKeyWithoutCondition {
@@ -188,8 +551,159 @@ func (t Tuple) isEmpty() bool {
 }
 
 // TimestampedTuple is a tuple accompanied by a timestamp that represents
-// the timestamp at which the tuple was created.
+// the timestamp at which the tuple was created. Any condition stored along
+// with the tuple is available in Tuple.Condition.
 type TimestampedTuple struct {
 	Tuple     Tuple
 	Timestamp time.Time
+	// HasTimestamp reports whether the timestamp was returned by the OpenFGA
+	// server. If false, Timestamp holds the zero time.Time value, which must
+	// not be used e.g. to order tuples.
+	HasTimestamp bool
+}
+
+// StripTimestamps converts a slice of timestamped tuples (e.g. as returned by
+// FindMatchingTuples) into a slice of the corresponding tuples.
+func StripTimestamps(ts []TimestampedTuple) []Tuple {
+	tuples := make([]Tuple, 0, len(ts))
+	for _, t := range ts {
+		tuples = append(tuples, t.Tuple)
+	}
+	return tuples
+}
+
+// GroupTuplesByRelation groups the given timestamped tuples (e.g. as returned
+// by FindMatchingTuples) by relation, preserving their order within each
+// group. This is useful, for instance, to render a summary of who has access
+// to an object.
+func GroupTuplesByRelation(tuples []TimestampedTuple) map[Relation][]TimestampedTuple {
+	groups := make(map[Relation][]TimestampedTuple)
+	for _, t := range tuples {
+		groups[t.Tuple.Relation] = append(groups[t.Tuple.Relation], t)
+	}
+	return groups
+}
+
+// TuplesFromStructs converts a slice of items, e.g. the relationships stored
+// in an application database, into a slice of tuples by calling fn on each
+// item. The result can be passed to AddRelation or RemoveRelation:
+//
+//	tuples := TuplesFromStructs(members, func(m Member) Tuple {
+//		return Tuple{
+//			Object:   &Entity{Kind: "user", ID: m.UserID},
+//			Relation: "member",
+//			Target:   &Entity{Kind: "team", ID: m.TeamID},
+//		}
+//	})
+//	err := client.AddRelation(ctx, tuples...)
+func TuplesFromStructs[T any](items []T, fn func(T) Tuple) []Tuple {
+	tuples := make([]Tuple, 0, len(items))
+	for _, item := range items {
+		tuples = append(tuples, fn(item))
+	}
+	return tuples
+}
+
+// ValidateTuplesAgainstModel validates the given tuples locally, so that
+// invalid tuples can be reported before writing them, e.g. when importing
+// data. Each tuple must be well-formed, and consistent with the given
+// authorization model: the type of the target must be defined in the model,
+// along with the relation, and the type of the object (including any
+// relation, wildcard and condition) must be one of the types directly related
+// to the relation. The condition of a tuple, if any, must also be defined in
+// the model, and its context must only hold parameters of the condition. If
+// any tuple is invalid, the returned error joins the errors of all the invalid
+// tuples (see errors.Join), each one reporting the index of the tuple and the
+// problem found.
+func ValidateTuplesAgainstModel(tuples []Tuple, model *openfga.AuthorizationModel) error {
+	var errs []error
+	for i, t := range tuples {
+		if err := validateTupleAgainstModel(t, model); err != nil {
+			errs = append(errs, fmt.Errorf("invalid tuple %d (%s): %v", i, t, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateTupleAgainstModel validates that the given tuple is well-formed and
+// can be written according to the given authorization model.
+func validateTupleAgainstModel(t Tuple, model *openfga.AuthorizationModel) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	i := slices.IndexFunc(model.TypeDefinitions, func(td openfga.TypeDefinition) bool {
+		return td.Type == t.Target.Kind.String()
+	})
+	if i == -1 {
+		return fmt.Errorf("type %q not found in the authorization model", t.Target.Kind)
+	}
+	td := model.TypeDefinitions[i]
+	if _, ok := td.GetRelations()[t.Relation.String()]; !ok {
+		return fmt.Errorf("relation %q not found for type %q", t.Relation, td.Type)
+	}
+	if t.Condition != nil {
+		if err := validateConditionAgainstModel(*t.Condition, model); err != nil {
+			return err
+		}
+	}
+	if model.SchemaVersion == "1.0" {
+		// Type restrictions are only available in models using schema 1.1.
+		return nil
+	}
+	metadata := td.GetMetadata()
+	rm := metadata.GetRelations()[t.Relation.String()]
+	for _, ref := range rm.GetDirectlyRelatedUserTypes() {
+		if ref.Type != t.Object.Kind.String() || ref.GetRelation() != t.Object.Relation.String() {
+			continue
+		}
+		if ref.HasWildcard() != t.Object.IsPublicAccess() {
+			continue
+		}
+		var condition string
+		if t.Condition != nil {
+			condition = t.Condition.Name
+		}
+		if ref.GetCondition() == condition {
+			return nil
+		}
+	}
+	allowed := fmt.Sprintf("%s#%s", td.Type, t.Relation)
+	if t.Condition != nil {
+		return fmt.Errorf("%s with condition %q cannot be directly related to %s", typeRestriction(*t.Object), t.Condition.Name, allowed)
+	}
+	return fmt.Errorf("%s cannot be directly related to %s", typeRestriction(*t.Object), allowed)
+}
+
+// validateConditionAgainstModel validates that the given condition is defined
+// in the given authorization model, and that its context only holds
+// parameters of the condition.
+func validateConditionAgainstModel(c Condition, model *openfga.AuthorizationModel) error {
+	condition, ok := model.GetConditions()[c.Name]
+	if !ok {
+		return fmt.Errorf("condition %q not found in the authorization model", c.Name)
+	}
+	params := condition.GetParameters()
+	keys := make([]string, 0, len(c.Context))
+	for k := range c.Context {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		if _, ok := params[k]; !ok {
+			return fmt.Errorf("unknown parameter %q in the context of condition %q", k, c.Name)
+		}
+	}
+	return nil
+}
+
+// typeRestriction returns the type restriction matching the given entity,
+// as used in the DSL (e.g. `user`, `user:*` or `group#member`).
+func typeRestriction(e Entity) string {
+	switch {
+	case e.IsPublicAccess():
+		return e.Kind.String() + ":*"
+	case e.IsEntitySet():
+		return e.Kind.String() + "#" + e.Relation.String()
+	}
+	return e.Kind.String()
 }
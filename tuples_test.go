@@ -5,6 +5,7 @@ package ofga_test
 
 import (
 	"testing"
+	"time"
 
 	qt "github.com/frankban/quicktest"
 	openfga "github.com/openfga/go-sdk"
@@ -151,6 +152,36 @@ func TestToOpenFGATuple(t *testing.T) {
 			User:   entityTestUser.String(),
 			Object: entityTestContract.String(),
 		},
+	}, {
+		about: "tuple with a condition is converted successfully",
+		tuple: ofga.Tuple{
+			Object:   &entityTestUser,
+			Relation: relationEditor,
+			Target:   &entityTestContract,
+			Condition: &ofga.Condition{
+				Name:    "in_office_hours",
+				Context: map[string]any{"timezone": "UTC"},
+			},
+		},
+		expectedOpenFGATupleKey: openfga.TupleKey{
+			User:     entityTestUser.String(),
+			Relation: relationEditor.String(),
+			Object:   entityTestContract.String(),
+			Condition: &openfga.RelationshipCondition{
+				Name:    "in_office_hours",
+				Context: &map[string]any{"timezone": "UTC"},
+			},
+		},
+	}, {
+		about: "tuple without target is converted successfully",
+		tuple: ofga.Tuple{
+			Object:   &entityTestUser,
+			Relation: relationEditor,
+		},
+		expectedOpenFGATupleKey: openfga.TupleKey{
+			User:     entityTestUser.String(),
+			Relation: relationEditor.String(),
+		},
 	}, {
 		about: "tuple with only target is converted successfully",
 		tuple: ofga.Tuple{
@@ -172,7 +203,7 @@ func TestToOpenFGATuple(t *testing.T) {
 	}
 }
 
-func TestTuplesToOpenFGATupleKeys(t *testing.T) {
+func TestTuplesToTupleKeys(t *testing.T) {
 	c := qt.New(t)
 
 	tests := []struct {
@@ -205,6 +236,26 @@ func TestTuplesToOpenFGATupleKeys(t *testing.T) {
 		}, {
 			Object: entityTestContract.String(),
 		}},
+	}, {
+		about: "conditions are preserved",
+		tuples: []ofga.Tuple{{
+			Object:   &entityTestUser,
+			Relation: relationEditor,
+			Target:   &entityTestContract,
+			Condition: &ofga.Condition{
+				Name:    "in_office_hours",
+				Context: map[string]any{"timezone": "UTC"},
+			},
+		}},
+		expectedOpenFGATupleKeys: []openfga.TupleKey{{
+			User:     entityTestUser.String(),
+			Relation: relationEditor.String(),
+			Object:   entityTestContract.String(),
+			Condition: &openfga.RelationshipCondition{
+				Name:    "in_office_hours",
+				Context: &map[string]any{"timezone": "UTC"},
+			},
+		}},
 	}}
 
 	for _, test := range tests {
@@ -212,12 +263,139 @@ func TestTuplesToOpenFGATupleKeys(t *testing.T) {
 		c.Run(test.about, func(c *qt.C) {
 			c.Parallel()
 
-			tupleKeys := ofga.TuplesToOpenFGATupleKeys(test.tuples)
+			tupleKeys := ofga.TuplesToTupleKeys(test.tuples)
 			c.Assert(tupleKeys, qt.DeepEquals, test.expectedOpenFGATupleKeys)
 		})
 	}
 }
 
+func TestStripTimestamps(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		about          string
+		tuples         []ofga.TimestampedTuple
+		expectedTuples []ofga.Tuple
+	}{{
+		about:          "empty slice of timestamped tuples returns empty slice of tuples",
+		tuples:         []ofga.TimestampedTuple{},
+		expectedTuples: []ofga.Tuple{},
+	}, {
+		about: "timestamps are stripped successfully",
+		tuples: []ofga.TimestampedTuple{{
+			Tuple: ofga.Tuple{
+				Object:   &entityTestUser,
+				Relation: relationEditor,
+				Target:   &entityTestContract,
+			},
+			Timestamp: time.Now(),
+		}, {
+			Tuple: ofga.Tuple{
+				Object:    &entityTestUser2,
+				Relation:  relationEditor,
+				Target:    &entityTestContract,
+				Condition: &ofga.Condition{Name: "in_office_hours"},
+			},
+		}},
+		expectedTuples: []ofga.Tuple{{
+			Object:   &entityTestUser,
+			Relation: relationEditor,
+			Target:   &entityTestContract,
+		}, {
+			Object:    &entityTestUser2,
+			Relation:  relationEditor,
+			Target:    &entityTestContract,
+			Condition: &ofga.Condition{Name: "in_office_hours"},
+		}},
+	}}
+
+	for _, test := range tests {
+		test := test
+		c.Run(test.about, func(c *qt.C) {
+			c.Parallel()
+
+			tuples := ofga.StripTimestamps(test.tuples)
+			c.Assert(tuples, qt.DeepEquals, test.expectedTuples)
+		})
+	}
+}
+
+func TestTuplesFromStructs(t *testing.T) {
+	c := qt.New(t)
+
+	type member struct {
+		userID string
+		teamID string
+	}
+	toTuple := func(m member) ofga.Tuple {
+		return ofga.Tuple{
+			Object:   &ofga.Entity{Kind: "user", ID: m.userID},
+			Relation: "member",
+			Target:   &ofga.Entity{Kind: "team", ID: m.teamID},
+		}
+	}
+
+	tuples := ofga.TuplesFromStructs([]member{{"alice", "a"}, {"bob", "b"}}, toTuple)
+	c.Assert(tuples, qt.DeepEquals, []ofga.Tuple{{
+		Object:   &ofga.Entity{Kind: "user", ID: "alice"},
+		Relation: "member",
+		Target:   &ofga.Entity{Kind: "team", ID: "a"},
+	}, {
+		Object:   &ofga.Entity{Kind: "user", ID: "bob"},
+		Relation: "member",
+		Target:   &ofga.Entity{Kind: "team", ID: "b"},
+	}})
+
+	tuples = ofga.TuplesFromStructs(nil, toTuple)
+	c.Assert(tuples, qt.DeepEquals, []ofga.Tuple{})
+}
+
+func TestGroupTuplesByRelation(t *testing.T) {
+	c := qt.New(t)
+
+	editor1 := ofga.TimestampedTuple{Tuple: ofga.Tuple{
+		Object:   &entityTestUser,
+		Relation: relationEditor,
+		Target:   &entityTestContract,
+	}}
+	editor2 := ofga.TimestampedTuple{Tuple: ofga.Tuple{
+		Object:   &entityTestUser2,
+		Relation: relationEditor,
+		Target:   &entityTestContract,
+	}}
+	viewer := ofga.TimestampedTuple{Tuple: ofga.Tuple{
+		Object:   &entityTestUser,
+		Relation: relationViewer,
+		Target:   &entityTestContract,
+	}}
+
+	tests := []struct {
+		about          string
+		tuples         []ofga.TimestampedTuple
+		expectedGroups map[ofga.Relation][]ofga.TimestampedTuple
+	}{{
+		about:          "empty slice of tuples returns an empty map",
+		expectedGroups: map[ofga.Relation][]ofga.TimestampedTuple{},
+	}, {
+		about:  "tuples are grouped by relation",
+		tuples: []ofga.TimestampedTuple{editor1, viewer, editor2},
+		expectedGroups: map[ofga.Relation][]ofga.TimestampedTuple{
+			relationEditor: {editor1, editor2},
+			relationViewer: {viewer},
+		},
+	}}
+
+	for _, test := range tests {
+		test := test
+		c.Run(test.about, func(c *qt.C) {
+			c.Parallel()
+
+			groups := ofga.GroupTuplesByRelation(test.tuples)
+			c.Assert(groups, qt.DeepEquals, test.expectedGroups)
+		})
+	}
+}
+
 func TestEntityString(t *testing.T) {
 	c := qt.New(t)
 
@@ -253,6 +431,47 @@ func TestEntityString(t *testing.T) {
 	}
 }
 
+func TestTupleString(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		about          string
+		tuple          ofga.Tuple
+		expectedString string
+	}{{
+		about: "tuple with all fields is converted successfully",
+		tuple: ofga.Tuple{
+			Object:   &entityTestUser,
+			Relation: relationEditor,
+			Target:   &entityTestContract,
+		},
+		expectedString: "user:123#editor@contract:789",
+	}, {
+		about: "tuple with an entity set object is converted successfully",
+		tuple: ofga.Tuple{
+			Object:   &ofga.Entity{Kind: "team", ID: "eng", Relation: "member"},
+			Relation: relationEditor,
+			Target:   &entityTestContract,
+		},
+		expectedString: "team:eng#member#editor@contract:789",
+	}, {
+		about: "tuple without object and target is converted successfully",
+		tuple: ofga.Tuple{
+			Relation: relationEditor,
+		},
+		expectedString: "#editor@",
+	}}
+
+	for _, test := range tests {
+		test := test
+		c.Run(test.about, func(c *qt.C) {
+			c.Parallel()
+
+			c.Assert(test.tuple.String(), qt.Equals, test.expectedString)
+		})
+	}
+}
+
 func TestEntityIsPublicAccess(t *testing.T) {
 	c := qt.New(t)
 
@@ -287,6 +506,330 @@ func TestEntityIsPublicAccess(t *testing.T) {
 	}
 }
 
+func TestPublicAccess(t *testing.T) {
+	c := qt.New(t)
+
+	e := ofga.PublicAccess("user")
+	c.Assert(*e, qt.DeepEquals, publicEntityUser)
+	c.Assert(e.IsPublicAccess(), qt.IsTrue)
+	c.Assert(e.String(), qt.Equals, "user:*")
+}
+
+func TestEntityIsEntitySet(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		about               string
+		entity              ofga.Entity
+		expectedIsEntitySet bool
+	}{{
+		about:               "entity is identified correctly",
+		entity:              ofga.Entity{Kind: "user", ID: "123"},
+		expectedIsEntitySet: false,
+	}, {
+		about:               "entity set is identified correctly",
+		entity:              ofga.Entity{Kind: "group", ID: "eng", Relation: "member"},
+		expectedIsEntitySet: true,
+	}}
+
+	for _, test := range tests {
+		test := test
+		c.Run(test.about, func(c *qt.C) {
+			c.Parallel()
+
+			c.Assert(test.entity.IsEntitySet(), qt.Equals, test.expectedIsEntitySet)
+		})
+	}
+}
+
+func TestTupleIsUsersetGrant(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		about                  string
+		tuple                  ofga.Tuple
+		expectedIsUsersetGrant bool
+	}{{
+		about: "tuple without an object is not a userset grant",
+		tuple: ofga.Tuple{
+			Relation: relationEditor,
+			Target:   &entityTestContract,
+		},
+		expectedIsUsersetGrant: false,
+	}, {
+		about: "tuple granting a relation to an entity is not a userset grant",
+		tuple: ofga.Tuple{
+			Object:   &entityTestUser,
+			Relation: relationEditor,
+			Target:   &entityTestContract,
+		},
+		expectedIsUsersetGrant: false,
+	}, {
+		about: "tuple granting a relation to an entity set is a userset grant",
+		tuple: ofga.Tuple{
+			Object:   &ofga.Entity{Kind: "group", ID: "eng", Relation: "member"},
+			Relation: relationEditor,
+			Target:   &entityTestContract,
+		},
+		expectedIsUsersetGrant: true,
+	}}
+
+	for _, test := range tests {
+		test := test
+		c.Run(test.about, func(c *qt.C) {
+			c.Parallel()
+
+			c.Assert(test.tuple.IsUsersetGrant(), qt.Equals, test.expectedIsUsersetGrant)
+		})
+	}
+}
+
+func TestRelationValidate(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		relation    ofga.Relation
+		expectedErr string
+	}{{
+		relation: "viewer",
+	}, {
+		relation: "can_view_2",
+	}, {
+		relation:    "",
+		expectedErr: "empty relation",
+	}, {
+		relation:    "View er",
+		expectedErr: `invalid relation "View er": relations must start with a lowercase letter and only contain lowercase letters, digits and underscores`,
+	}, {
+		relation:    "Viewer",
+		expectedErr: `invalid relation "Viewer": .*`,
+	}, {
+		relation:    "2viewer",
+		expectedErr: `invalid relation "2viewer": .*`,
+	}, {
+		relation:    "_viewer",
+		expectedErr: `invalid relation "_viewer": .*`,
+	}, {
+		relation:    "can-view",
+		expectedErr: `invalid relation "can-view": .*`,
+	}, {
+		relation:    "member#admin",
+		expectedErr: `invalid relation "member#admin": .*`,
+	}}
+
+	for _, test := range tests {
+		test := test
+		c.Run(string(test.relation), func(c *qt.C) {
+			c.Parallel()
+
+			err := test.relation.Validate()
+			if test.expectedErr != "" {
+				c.Assert(err, qt.ErrorMatches, test.expectedErr)
+			} else {
+				c.Assert(err, qt.IsNil)
+			}
+		})
+	}
+}
+
+func TestTupleValidate(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		about       string
+		tuple       ofga.Tuple
+		expectedErr string
+	}{{
+		about: "valid tuple",
+		tuple: ofga.Tuple{
+			Object:   &ofga.Entity{Kind: "team", ID: "eng", Relation: "member"},
+			Relation: "viewer",
+			Target:   &ofga.Entity{Kind: "document", ID: "abc"},
+		},
+	}, {
+		about: "valid tuple with condition",
+		tuple: ofga.Tuple{
+			Object:    ofga.PublicAccess("user"),
+			Relation:  "viewer",
+			Target:    &ofga.Entity{Kind: "document", ID: "abc"},
+			Condition: &ofga.Condition{Name: "in_office_hours"},
+		},
+	}, {
+		about: "missing object",
+		tuple: ofga.Tuple{
+			Relation: "viewer",
+			Target:   &ofga.Entity{Kind: "document", ID: "abc"},
+		},
+		expectedErr: "missing object",
+	}, {
+		about: "invalid object",
+		tuple: ofga.Tuple{
+			Object:   &ofga.Entity{Kind: "user", ID: "a:b"},
+			Relation: "viewer",
+			Target:   &ofga.Entity{Kind: "document", ID: "abc"},
+		},
+		expectedErr: `invalid tuple object: invalid entity ID "a:b"`,
+	}, {
+		about: "missing relation",
+		tuple: ofga.Tuple{
+			Object: &ofga.Entity{Kind: "user", ID: "bob"},
+			Target: &ofga.Entity{Kind: "document", ID: "abc"},
+		},
+		expectedErr: "missing relation",
+	}, {
+		about: "invalid relation",
+		tuple: ofga.Tuple{
+			Object:   &ofga.Entity{Kind: "user", ID: "bob"},
+			Relation: "can view",
+			Target:   &ofga.Entity{Kind: "document", ID: "abc"},
+		},
+		expectedErr: `invalid tuple relation: "can view"`,
+	}, {
+		about: "missing target",
+		tuple: ofga.Tuple{
+			Object:   &ofga.Entity{Kind: "user", ID: "bob"},
+			Relation: "viewer",
+		},
+		expectedErr: "missing target",
+	}, {
+		about: "target without ID",
+		tuple: ofga.Tuple{
+			Object:   &ofga.Entity{Kind: "user", ID: "bob"},
+			Relation: "viewer",
+			Target:   &ofga.Entity{Kind: "document"},
+		},
+		expectedErr: `invalid tuple target: invalid entity ID ""`,
+	}, {
+		about: "target with invalid kind",
+		tuple: ofga.Tuple{
+			Object:   &ofga.Entity{Kind: "user", ID: "bob"},
+			Relation: "viewer",
+			Target:   &ofga.Entity{Kind: "docu ment", ID: "abc"},
+		},
+		expectedErr: `invalid tuple target: invalid entity kind "docu ment"`,
+	}, {
+		about: "entity set target",
+		tuple: ofga.Tuple{
+			Object:   &ofga.Entity{Kind: "user", ID: "bob"},
+			Relation: "member",
+			Target:   &ofga.Entity{Kind: "team", ID: "eng", Relation: "member"},
+		},
+		expectedErr: "target must not be an entity set",
+	}, {
+		about: "condition without name",
+		tuple: ofga.Tuple{
+			Object:    &ofga.Entity{Kind: "user", ID: "bob"},
+			Relation:  "viewer",
+			Target:    &ofga.Entity{Kind: "document", ID: "abc"},
+			Condition: &ofga.Condition{},
+		},
+		expectedErr: "missing condition name",
+	}}
+
+	for _, test := range tests {
+		test := test
+		c.Run(test.about, func(c *qt.C) {
+			err := test.tuple.Validate()
+			if test.expectedErr != "" {
+				c.Assert(err, qt.ErrorMatches, test.expectedErr)
+			} else {
+				c.Assert(err, qt.IsNil)
+			}
+		})
+	}
+}
+
+func TestNewEntity(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		about          string
+		kind           ofga.Kind
+		id             string
+		relation       ofga.Relation
+		entitySet      bool
+		expectedEntity ofga.Entity
+		expectedErr    string
+	}{{
+		about:       "entity with an empty kind is rejected",
+		id:          "123",
+		expectedErr: `invalid entity kind: ""`,
+	}, {
+		about:       "entity with an invalid kind is rejected",
+		kind:        "user:admin",
+		id:          "123",
+		expectedErr: `invalid entity kind: "user:admin"`,
+	}, {
+		about:       "entity with an empty ID is rejected",
+		kind:        "user",
+		expectedErr: `invalid entity ID: ""`,
+	}, {
+		about:       "entity with an invalid ID is rejected",
+		kind:        "user",
+		id:          "123#member",
+		expectedErr: `invalid entity ID: "123#member"`,
+	}, {
+		about:          "entity is created successfully",
+		kind:           "user",
+		id:             "alice@canonical.com",
+		expectedEntity: ofga.Entity{Kind: "user", ID: "alice@canonical.com"},
+	}, {
+		about:          "public access entity is created successfully",
+		kind:           "user",
+		id:             "*",
+		expectedEntity: ofga.Entity{Kind: "user", ID: "*"},
+	}, {
+		about:       "entity set with an invalid ID is rejected",
+		kind:        "organization",
+		id:          "canonical:123",
+		relation:    "member",
+		entitySet:   true,
+		expectedErr: `invalid entity ID: "canonical:123"`,
+	}, {
+		about:       "entity set with an empty relation is rejected",
+		kind:        "organization",
+		id:          "canonical",
+		entitySet:   true,
+		expectedErr: `invalid entity relation: ""`,
+	}, {
+		about:       "entity set with an invalid relation is rejected",
+		kind:        "organization",
+		id:          "canonical",
+		relation:    "member#admin",
+		entitySet:   true,
+		expectedErr: `invalid entity relation: "member#admin"`,
+	}, {
+		about:          "entity set is created successfully",
+		kind:           "organization",
+		id:             "canonical",
+		relation:       "member",
+		entitySet:      true,
+		expectedEntity: ofga.Entity{Kind: "organization", ID: "canonical", Relation: "member"},
+	}}
+
+	for _, test := range tests {
+		test := test
+		c.Run(test.about, func(c *qt.C) {
+			c.Parallel()
+
+			var entity ofga.Entity
+			var err error
+			if test.entitySet {
+				entity, err = ofga.NewEntitySet(test.kind, test.id, test.relation)
+			} else {
+				entity, err = ofga.NewEntity(test.kind, test.id)
+			}
+
+			if test.expectedErr != "" {
+				c.Assert(err, qt.ErrorMatches, test.expectedErr)
+			} else {
+				c.Assert(err, qt.IsNil)
+				c.Assert(entity, qt.DeepEquals, test.expectedEntity)
+			}
+		})
+	}
+}
+
 func TestParseEntity(t *testing.T) {
 	c := qt.New(t)
 
@@ -349,6 +892,30 @@ func TestParseEntity(t *testing.T) {
 		about:        "wildcard entity with extra characters raises an error",
 		entityString: "user:*foo",
 		expectedErr:  "invalid entity representation.*",
+	}, {
+		about:        "entity with a colon in the ID raises an error",
+		entityString: "document:urn:isbn:0451450523",
+		expectedErr:  `invalid entity representation: document:urn:isbn:0451450523: entity IDs cannot contain the reserved ":" and "#" characters, use EncodeID to encode them`,
+	}, {
+		about:        "entity with a hash in the ID and a relation raises an error",
+		entityString: "document:a#b#viewer",
+		expectedErr:  `invalid entity representation: document:a#b#viewer: entity IDs cannot contain the reserved ":" and "#" characters, use EncodeID to encode them`,
+	}, {
+		about:        "entity with leading invalid characters raises an error",
+		entityString: "not valid:user:123",
+		expectedErr:  `invalid entity representation: not valid:user:123`,
+	}, {
+		about:        "entity with an invalid kind raises an error",
+		entityString: "-user:123",
+		expectedErr:  `invalid entity representation: -user:123`,
+	}, {
+		about:        "entity with an encoded ID is parsed correctly",
+		entityString: "document:urn%3Aisbn%3A0451450523#viewer",
+		expectedEntity: ofga.Entity{
+			Kind:     "document",
+			ID:       "urn%3Aisbn%3A0451450523",
+			Relation: "viewer",
+		},
 	}}
 
 	for _, test := range tests {
@@ -368,6 +935,264 @@ func TestParseEntity(t *testing.T) {
 	}
 }
 
+func TestParseEntityPrefix(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		about          string
+		s              string
+		expectedEntity ofga.Entity
+		expectedErr    string
+	}{{
+		about:          "type-only representation is parsed",
+		s:              "document:",
+		expectedEntity: ofga.Entity{Kind: "document"},
+	}, {
+		about:          "full entity is parsed",
+		s:              "document:readme",
+		expectedEntity: ofga.Entity{Kind: "document", ID: "readme"},
+	}, {
+		about:          "entity set is parsed",
+		s:              "organization:canonical#member",
+		expectedEntity: ofga.Entity{Kind: "organization", ID: "canonical", Relation: "member"},
+	}, {
+		about:       "type-only representation with a relation is rejected",
+		s:           "organization:#member",
+		expectedErr: "invalid entity representation: organization:#member",
+	}, {
+		about:       "missing colon is rejected",
+		s:           "document",
+		expectedErr: "invalid entity representation: document",
+	}, {
+		about:       "empty kind is rejected",
+		s:           ":",
+		expectedErr: "invalid entity representation: :",
+	}}
+
+	for _, test := range tests {
+		test := test
+		c.Run(test.about, func(c *qt.C) {
+			entity, err := ofga.ParseEntityPrefix(test.s)
+			if test.expectedErr != "" {
+				c.Assert(err, qt.ErrorMatches, test.expectedErr)
+				c.Assert(entity, qt.DeepEquals, ofga.Entity{})
+			} else {
+				c.Assert(err, qt.IsNil)
+				c.Assert(entity, qt.DeepEquals, test.expectedEntity)
+			}
+		})
+	}
+}
+
+func TestParseEntities(t *testing.T) {
+	c := qt.New(t)
+
+	entities, errs := ofga.ParseEntities([]string{"user:alice", "invalid", "organization:canonical#member"})
+	c.Assert(entities, qt.DeepEquals, []ofga.Entity{
+		{Kind: "user", ID: "alice"},
+		{},
+		{Kind: "organization", ID: "canonical", Relation: "member"},
+	})
+	c.Assert(errs, qt.HasLen, 3)
+	c.Assert(errs[0], qt.IsNil)
+	c.Assert(errs[1], qt.ErrorMatches, "invalid entity representation: invalid")
+	c.Assert(errs[2], qt.IsNil)
+
+	entities, errs = ofga.ParseEntities(nil)
+	c.Assert(entities, qt.HasLen, 0)
+	c.Assert(errs, qt.HasLen, 0)
+}
+
+func TestEncodeDecodeID(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		about     string
+		id        string
+		encodedID string
+	}{{
+		about:     "simple ID is left unchanged",
+		id:        "some.user-name+suffix@some.domain-name+suffix",
+		encodedID: "some.user-name+suffix@some.domain-name+suffix",
+	}, {
+		about:     "reserved characters are encoded",
+		id:        "urn:isbn:0451450523#1",
+		encodedID: "urn%3Aisbn%3A0451450523%231",
+	}, {
+		about:     "percent signs, slashes and spaces are encoded",
+		id:        "a/b c%d",
+		encodedID: "a%2Fb%20c%25d",
+	}, {
+		about:     "non-ASCII characters are encoded",
+		id:        "café",
+		encodedID: "caf%C3%A9",
+	}}
+
+	for _, test := range tests {
+		test := test
+		c.Run(test.about, func(c *qt.C) {
+			c.Parallel()
+
+			encodedID := ofga.EncodeID(test.id)
+			c.Assert(encodedID, qt.Equals, test.encodedID)
+
+			e, err := ofga.NewEntity("document", encodedID)
+			c.Assert(err, qt.IsNil)
+			parsed, err := ofga.ParseEntity(e.String())
+			c.Assert(err, qt.IsNil)
+			c.Assert(parsed, qt.DeepEquals, e)
+
+			id, err := ofga.DecodeID(parsed.ID)
+			c.Assert(err, qt.IsNil)
+			c.Assert(id, qt.Equals, test.id)
+		})
+	}
+}
+
+func TestDecodeIDError(t *testing.T) {
+	c := qt.New(t)
+
+	_, err := ofga.DecodeID("abc%2")
+	c.Assert(err, qt.ErrorMatches, `invalid encoded entity ID: "abc%2"`)
+}
+
+func TestParseTuple(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		about         string
+		object        string
+		relation      string
+		target        string
+		expectedTuple ofga.Tuple
+		expectedErr   string
+	}{{
+		about:       "tuple with malformed object raises error",
+		object:      "user#XYZ",
+		relation:    "member",
+		target:      "organization:canonical",
+		expectedErr: "invalid tuple object: invalid entity representation.*",
+	}, {
+		about:       "tuple with malformed relation raises error",
+		object:      "user:XYZ",
+		relation:    "member:admin",
+		target:      "organization:canonical",
+		expectedErr: `invalid tuple relation: "member:admin"`,
+	}, {
+		about:       "tuple with missing target raises error",
+		object:      "user:XYZ",
+		relation:    "member",
+		expectedErr: "invalid tuple target: invalid entity representation.*",
+	}, {
+		about:    "tuple with all fields is parsed successfully",
+		object:   "team:eng#member",
+		relation: "member",
+		target:   "organization:canonical",
+		expectedTuple: ofga.Tuple{
+			Object:   &ofga.Entity{Kind: "team", ID: "eng", Relation: "member"},
+			Relation: "member",
+			Target:   &ofga.Entity{Kind: "organization", ID: "canonical"},
+		},
+	}, {
+		about:  "tuple with only a target is parsed successfully",
+		target: "organization:canonical",
+		expectedTuple: ofga.Tuple{
+			Target: &ofga.Entity{Kind: "organization", ID: "canonical"},
+		},
+	}}
+
+	for _, test := range tests {
+		test := test
+		c.Run(test.about, func(c *qt.C) {
+			c.Parallel()
+
+			tuple, err := ofga.ParseTuple(test.object, test.relation, test.target)
+
+			if test.expectedErr != "" {
+				c.Assert(err, qt.ErrorMatches, test.expectedErr)
+			} else {
+				c.Assert(err, qt.IsNil)
+				c.Assert(tuple, qt.DeepEquals, test.expectedTuple)
+			}
+		})
+	}
+}
+
+func TestParseTupleString(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		about         string
+		s             string
+		expectedTuple ofga.Tuple
+		expectedErr   string
+	}{{
+		about:       "string without relation separator raises error",
+		s:           "user:XYZ@organization:canonical",
+		expectedErr: "invalid tuple representation: user:XYZ@organization:canonical",
+	}, {
+		about:       "string without target separator raises error",
+		s:           "user:XYZ#member",
+		expectedErr: "invalid tuple representation: user:XYZ#member",
+	}, {
+		about:       "tuple with malformed object raises error",
+		s:           "user#member@organization:canonical",
+		expectedErr: "invalid tuple object: invalid entity representation.*",
+	}, {
+		about:       "tuple with missing target raises error",
+		s:           "user:XYZ#member@",
+		expectedErr: "invalid tuple target: invalid entity representation.*",
+	}, {
+		about: "tuple with all fields is parsed successfully",
+		s:     "user:123#editor@contract:789",
+		expectedTuple: ofga.Tuple{
+			Object:   &entityTestUser,
+			Relation: relationEditor,
+			Target:   &entityTestContract,
+		},
+	}, {
+		about: "tuple with an entity set object is parsed successfully",
+		s:     "team:eng#member#editor@contract:789",
+		expectedTuple: ofga.Tuple{
+			Object:   &ofga.Entity{Kind: "team", ID: "eng", Relation: "member"},
+			Relation: relationEditor,
+			Target:   &entityTestContract,
+		},
+	}, {
+		about: "tuple with IDs including the @ character is parsed successfully",
+		s:     "user:alice@example.com#editor@contract:bob@example.com",
+		expectedTuple: ofga.Tuple{
+			Object:   &ofga.Entity{Kind: "user", ID: "alice@example.com"},
+			Relation: relationEditor,
+			Target:   &ofga.Entity{Kind: "contract", ID: "bob@example.com"},
+		},
+	}, {
+		about: "tuple with only a target is parsed successfully",
+		s:     "#@organization:canonical",
+		expectedTuple: ofga.Tuple{
+			Target: &ofga.Entity{Kind: "organization", ID: "canonical"},
+		},
+	}}
+
+	for _, test := range tests {
+		test := test
+		c.Run(test.about, func(c *qt.C) {
+			c.Parallel()
+
+			tuple, err := ofga.ParseTupleString(test.s)
+
+			if test.expectedErr != "" {
+				c.Assert(err, qt.ErrorMatches, test.expectedErr)
+			} else {
+				c.Assert(err, qt.IsNil)
+				c.Assert(tuple, qt.DeepEquals, test.expectedTuple)
+				// The string representation round-trips.
+				c.Assert(tuple.String(), qt.Equals, test.s)
+			}
+		})
+	}
+}
+
 func TestFromOpenFGATupleKey(t *testing.T) {
 	c := qt.New(t)
 
@@ -404,6 +1229,26 @@ func TestFromOpenFGATupleKey(t *testing.T) {
 			Relation: "member",
 			Target:   &ofga.Entity{Kind: "organization", ID: "canonical"},
 		},
+	}, {
+		about: "tuple with a condition is converted successfully",
+		tupleKey: openfga.TupleKey{
+			User:     "user:XYZ",
+			Relation: "member",
+			Object:   "organization:canonical",
+			Condition: &openfga.RelationshipCondition{
+				Name:    "in_office_hours",
+				Context: &map[string]any{"timezone": "UTC"},
+			},
+		},
+		expectedTuple: ofga.Tuple{
+			Object:   &ofga.Entity{Kind: "user", ID: "XYZ"},
+			Relation: "member",
+			Target:   &ofga.Entity{Kind: "organization", ID: "canonical"},
+			Condition: &ofga.Condition{
+				Name:    "in_office_hours",
+				Context: map[string]any{"timezone": "UTC"},
+			},
+		},
 	}}
 
 	for _, test := range tests {
@@ -475,3 +1320,101 @@ func TestTuple_IsEmpty(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateTuplesAgainstModel(t *testing.T) {
+	c := qt.New(t)
+
+	model, err := ofga.AuthModelFromJSON(fullAuthModelJson)
+	c.Assert(err, qt.IsNil)
+
+	tuple := func(object, relation, target string) ofga.Tuple {
+		t, err := ofga.ParseTuple(object, relation, target)
+		c.Assert(err, qt.IsNil)
+		return t
+	}
+	withCondition := func(t ofga.Tuple, name string) ofga.Tuple {
+		t.Condition = &ofga.Condition{Name: name}
+		return t
+	}
+
+	tests := []struct {
+		about       string
+		tuples      []ofga.Tuple
+		expectedErr string
+	}{{
+		about: "no tuples",
+	}, {
+		about: "valid tuples",
+		tuples: []ofga.Tuple{
+			tuple("user:alice", "owner", "document:planning"),
+			tuple("group:eng#member", "editor", "document:planning"),
+			tuple("user:*", "viewer", "document:planning"),
+			tuple("document:root", "parent", "document:planning"),
+			tuple("group:eng#member", "member", "group:all"),
+			withCondition(tuple("user:bob", "editor", "document:planning"), "in_office_hours"),
+		},
+	}, {
+		about:       "malformed tuple",
+		tuples:      []ofga.Tuple{{Relation: "owner", Target: &ofga.Entity{Kind: "document", ID: "planning"}}},
+		expectedErr: `invalid tuple 0 \(.*\): missing object`,
+	}, {
+		about:       "unknown type",
+		tuples:      []ofga.Tuple{tuple("user:alice", "owner", "folder:planning")},
+		expectedErr: `invalid tuple 0 \(user:alice#owner@folder:planning\): type "folder" not found in the authorization model`,
+	}, {
+		about:       "unknown relation",
+		tuples:      []ofga.Tuple{tuple("user:alice", "admin", "document:planning")},
+		expectedErr: `invalid tuple 0 \(user:alice#admin@document:planning\): relation "admin" not found for type "document"`,
+	}, {
+		about:       "unknown condition",
+		tuples:      []ofga.Tuple{withCondition(tuple("user:alice", "editor", "document:planning"), "on_weekends")},
+		expectedErr: `invalid tuple 0 \(.*\): condition "on_weekends" not found in the authorization model`,
+	}, {
+		about: "unknown condition context parameter",
+		tuples: func() []ofga.Tuple {
+			t := withCondition(tuple("user:alice", "editor", "document:planning"), "in_office_hours")
+			t.Condition.Context = map[string]any{"allowed": []string{"alice"}, "timezone": "UTC"}
+			return []ofga.Tuple{t}
+		}(),
+		expectedErr: `invalid tuple 0 \(.*\): unknown parameter "timezone" in the context of condition "in_office_hours"`,
+	}, {
+		about:       "relation that cannot be assigned directly",
+		tuples:      []ofga.Tuple{tuple("user:alice", "auditor", "document:planning")},
+		expectedErr: `invalid tuple 0 \(.*\): user cannot be directly related to document#auditor`,
+	}, {
+		about:       "disallowed wildcard",
+		tuples:      []ofga.Tuple{tuple("user:*", "owner", "document:planning")},
+		expectedErr: `invalid tuple 0 \(.*\): user:\* cannot be directly related to document#owner`,
+	}, {
+		about:       "disallowed userset",
+		tuples:      []ofga.Tuple{tuple("group:eng#member", "owner", "document:planning")},
+		expectedErr: `invalid tuple 0 \(.*\): group#member cannot be directly related to document#owner`,
+	}, {
+		about:       "missing condition",
+		tuples:      []ofga.Tuple{withCondition(tuple("user:alice", "owner", "document:planning"), "in_office_hours")},
+		expectedErr: `invalid tuple 0 \(.*\): user with condition "in_office_hours" cannot be directly related to document#owner`,
+	}, {
+		about: "all invalid tuples are reported",
+		tuples: []ofga.Tuple{
+			tuple("user:alice", "owner", "folder:planning"),
+			tuple("user:alice", "owner", "document:planning"),
+			tuple("user:alice", "admin", "document:planning"),
+		},
+		expectedErr: `invalid tuple 0 \(.*\): type "folder" not found in the authorization model\n` +
+			`invalid tuple 2 \(.*\): relation "admin" not found for type "document"`,
+	}}
+
+	for _, test := range tests {
+		test := test
+		c.Run(test.about, func(c *qt.C) {
+			c.Parallel()
+
+			err := ofga.ValidateTuplesAgainstModel(test.tuples, model)
+			if test.expectedErr != "" {
+				c.Assert(err, qt.ErrorMatches, test.expectedErr)
+			} else {
+				c.Assert(err, qt.IsNil)
+			}
+		})
+	}
+}